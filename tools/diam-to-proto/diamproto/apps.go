@@ -0,0 +1,94 @@
+package diamproto
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+)
+
+// apps holds aliases of the common 3GPP interfaces and their application IDs.
+var apps = map[string]uint32{
+	"base": 0,
+	"rf":   3,
+	"ro":   4,
+	"gy":   4,
+	"cx":   16777216,
+	"dx":   16777216,
+	"sh":   16777217,
+	"rx":   16777236,
+	"gx":   16777238,
+	"s6a":  16777251,
+	"s6d":  16777251,
+	"swx":  16777265,
+	"gxx":  16777266,
+	"s6b":  16777272,
+	"sy":   16777302,
+	"sd":   16777303,
+	"st":   16777349,
+}
+
+// normalizeAppName reduces an application name to lower case letters and
+// digits, without the 3GPP prefix, e.g. "TGPP S6A" becomes "s6a".
+func normalizeAppName(name string) string {
+	name = strings.ToLower(name)
+	for _, prefix := range []string{"tgpp", "3gpp"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return strings.Map(func(r rune) rune {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// ResolveApplication returns the ID of the application an -intf token refers
+// to, along with the key naming its proto file and types. The token is either
+// an alias from apps, a numeric application ID or an application name found
+// in the loaded dictionaries. The application must be present in the
+// dictionaries.
+func ResolveApplication(p *dict.Parser, token string) (uint32, string, error) {
+	token = strings.TrimSpace(token)
+	loaded := make(map[uint32]*dict.App)
+	for _, app := range p.Apps() {
+		if _, ok := loaded[app.ID]; !ok || len(app.Command) > 0 {
+			loaded[app.ID] = app
+		}
+	}
+
+	if id, ok := apps[strings.ToLower(token)]; ok {
+		if _, ok := loaded[id]; !ok {
+			return 0, "", fmt.Errorf("application %s (%d) not found in loaded dictionaries", token, id)
+		}
+		return id, strings.ToLower(token), nil
+	}
+
+	if id, err := strconv.ParseUint(token, 10, 32); err == nil {
+		app, ok := loaded[uint32(id)]
+		if !ok {
+			return 0, "", fmt.Errorf("application %d not found in loaded dictionaries", id)
+		}
+		key := normalizeAppName(app.Name)
+		if key == "" || ('0' <= key[0] && key[0] <= '9') {
+			key = fmt.Sprintf("app%d", id)
+		}
+		return uint32(id), key, nil
+	}
+
+	name := normalizeAppName(token)
+	for _, app := range p.Apps() {
+		if name != "" && normalizeAppName(app.Name) == name {
+			return app.ID, name, nil
+		}
+	}
+
+	var known []string
+	for _, app := range p.Apps() {
+		known = append(known, fmt.Sprintf("%s (%d)", app.Name, app.ID))
+	}
+	sort.Strings(known)
+	return 0, "", fmt.Errorf("unknown application %q, expected an alias, an ID or one of: %s", token, strings.Join(known, ", "))
+}
@@ -0,0 +1,49 @@
+package diamproto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveApplication(t *testing.T) {
+	d := extraDictionary(t, `<?xml version="1.0" encoding="UTF-8"?>
+<diameter>
+	<application id="16777302" type="auth" name="TGPP Sy">
+		<vendor id="10415" name="TGPP"/>
+	</application>
+</diameter>`)
+	for _, tc := range []struct {
+		token string
+		id    uint32
+		key   string
+		err   string
+	}{
+		{token: "gx", id: 16777238, key: "gx"},
+		{token: " GY ", id: 4, key: "gy"},
+		{token: "ro", id: 4, key: "ro"},
+		{token: "base", id: 0, key: "base"},
+		{token: "sy", id: 16777302, key: "sy"},
+		{token: "16777238", id: 16777238, key: "gxchargingcontrol"},
+		{token: "16777302", id: 16777302, key: "sy"},
+		{token: "0", id: 0, key: "base"},
+		{token: "Charging Control", id: 4, key: "chargingcontrol"},
+		{token: "3GPP Sy", id: 16777302, key: "sy"},
+		{token: "sh", err: "application sh (16777217) not found"},
+		{token: "16777217", err: "application 16777217 not found"},
+		{token: "nope", err: `unknown application "nope"`},
+		{token: "", err: `unknown application ""`},
+	} {
+		id, key, err := ResolveApplication(d.P, tc.token)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%q: got error %v, want %q", tc.token, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tc.token, err)
+		} else if id != tc.id || key != tc.key {
+			t.Errorf("%q: got application %d key %s, want %d key %s", tc.token, id, key, tc.id, tc.key)
+		}
+	}
+}
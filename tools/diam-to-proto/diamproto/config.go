@@ -0,0 +1,131 @@
+package diamproto
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a generation run, so that it can be reproduced from a
+// checked-in file. Files are read as YAML, of which JSON is a subset, and
+// relative paths are relative to the directory of the file.
+type Config struct {
+	// Dictionaries are the folders to load, in order.
+	Dictionaries []string `yaml:"dictionaries" json:"dictionaries"`
+	// Interfaces are the aliases, application names or IDs to generate.
+	Interfaces []string `yaml:"interfaces" json:"interfaces"`
+	// Commands restricts the commands generated for an interface, keyed as
+	// in Interfaces, to the listed command names or codes.
+	Commands map[string][]string `yaml:"commands" json:"commands"`
+	// ExcludeCommands lists, keyed as in Interfaces, command names or codes
+	// not to generate.
+	ExcludeCommands map[string][]string `yaml:"excludeCommands" json:"excludeCommands"`
+	// Out, GoOut and Lock are the output directories of the proto files and
+	// Go converters, and the lock file.
+	Out   string `yaml:"out" json:"out"`
+	GoOut string `yaml:"goOut" json:"goOut"`
+	Lock  string `yaml:"lock" json:"lock"`
+
+	Package          string `yaml:"package" json:"package"`
+	GoPackage        string `yaml:"goPackage" json:"goPackage"`
+	OptionsGoPackage string `yaml:"optionsGoPackage" json:"optionsGoPackage"`
+	NumberFormat     string `yaml:"numberFormat" json:"numberFormat"`
+	Conflict         string `yaml:"conflict" json:"conflict"`
+	Optional         bool   `yaml:"optional" json:"optional"`
+	Services         bool   `yaml:"services" json:"services"`
+	UnknownAvps      bool   `yaml:"unknownAvps" json:"unknownAvps"`
+	Envelopes        bool   `yaml:"envelopes" json:"envelopes"`
+	// TypeMap maps Diameter data types to requiredType[:optionalType].
+	TypeMap map[string]string `yaml:"typeMap" json:"typeMap"`
+	Renames Renames           `yaml:"renames" json:"renames"`
+	// Overrides changes the types and names generated for AVPs, keyed by
+	// AVP name, code:vendor or code.
+	Overrides map[string]Override `yaml:"overrides" json:"overrides"`
+	// Oneofs groups alternative fields of a message, keyed by message name
+	// then oneof name.
+	Oneofs map[string]map[string][]string `yaml:"oneofs" json:"oneofs"`
+}
+
+// Renames changes the names of generated messages, enums and fields. Keys
+// are the names the generator would use otherwise, fields being given as
+// Message.field.
+type Renames struct {
+	Messages map[string]string `yaml:"messages" json:"messages"`
+	Fields   map[string]string `yaml:"fields" json:"fields"`
+}
+
+// DefaultConfig returns the configuration used when no file is given, which
+// matches the defaults of the command line flags.
+func DefaultConfig() *Config {
+	return &Config{
+		Dictionaries: []string{"./dict"},
+		Interfaces:   []string{"gx", "gy"},
+		Package:      "diameterpb",
+		NumberFormat: NumberSeq,
+		Conflict:     ConflictMerge,
+	}
+}
+
+// ReadConfig reads the configuration file at path. Settings missing from the
+// file keep the values of DefaultConfig.
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := DefaultConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i, folder := range config.Dictionaries {
+		config.Dictionaries[i] = resolvePath(dir, folder)
+	}
+	config.Out = resolvePath(dir, config.Out)
+	config.GoOut = resolvePath(dir, config.GoOut)
+	config.Lock = resolvePath(dir, config.Lock)
+	return config, nil
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// Options returns the generator options of the configuration.
+func (c *Config) Options() (Options, error) {
+	opts := Options{
+		Interfaces:       c.Interfaces,
+		Commands:         c.Commands,
+		ExcludeCommands:  c.ExcludeCommands,
+		NumberFormat:     c.NumberFormat,
+		Package:          c.Package,
+		GoPackage:        c.GoPackage,
+		OptionsGoPackage: c.OptionsGoPackage,
+		Conflict:         c.Conflict,
+		Optional:         c.Optional,
+		Services:         c.Services,
+		UnknownAvps:      c.UnknownAvps,
+		Envelopes:        c.Envelopes,
+		TypeMap:          TypeMap{},
+		Renames:          c.Renames,
+		Overrides:        c.Overrides,
+		Oneofs:           c.Oneofs,
+	}
+	var names []string
+	for name := range c.TypeMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := opts.TypeMap.Set(name + "=" + c.TypeMap[name]); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
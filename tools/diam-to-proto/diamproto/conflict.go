@@ -0,0 +1,277 @@
+package diamproto
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Conflict resolution strategies for grouped and enumerated types that are
+// built differently by the enabled applications.
+const (
+	ConflictMerge     = "merge"
+	ConflictNamespace = "namespace"
+	ConflictFail      = "fail"
+)
+
+// typeVariants holds the variants of a type built by every application, in
+// build order.
+type typeVariants struct {
+	apps  []uint32
+	byApp map[uint32]CompositeField
+}
+
+// record records the variant of a type built for an application. Conflicts
+// between variants are resolved by resolveConflicts once every application
+// has been built.
+func (g *Generator) record(appId uint32, dataType string, compField CompositeField) {
+	v, ok := g.variants[dataType]
+	if !ok {
+		v = &typeVariants{byApp: make(map[uint32]CompositeField)}
+		g.variants[dataType] = v
+	}
+	if _, ok := v.byApp[appId]; !ok {
+		v.apps = append(v.apps, appId)
+	}
+	v.byApp[appId] = compField
+}
+
+// distinct returns the first application building each distinct variant.
+func (v *typeVariants) distinct() []uint32 {
+	var apps []uint32
+	for _, appId := range v.apps {
+		unique := true
+		for _, other := range apps {
+			if sameFields(v.byApp[appId], v.byApp[other]) {
+				unique = false
+				break
+			}
+		}
+		if unique {
+			apps = append(apps, appId)
+		}
+	}
+	return apps
+}
+
+func sameFields(a, b CompositeField) bool {
+	if len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for i := range a.Fields {
+		if fieldSignature(a.Fields[i]) != fieldSignature(b.Fields[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldSignature(f Field) string {
+	switch field := f.(type) {
+	case *GeneralField:
+		return fmt.Sprintf("%s %s code=%d vendor=%d repeated=%t required=%t min=%d max=%d",
+			field.DataType, field.VarName, field.AvpCode, field.VendorId, field.Repeated, field.Required, field.Min, field.Max)
+	case *EnumField:
+		return fmt.Sprintf("%s=%d", field.Name, field.Code)
+	}
+	return fmt.Sprint(f)
+}
+
+// resolveConflicts fills the parsed types from the recorded variants using
+// the conflict strategy of the options. appNames and appPrefixes name the
+// applications in reports and namespaced types respectively.
+func (g *Generator) resolveConflicts(appNames, appPrefixes map[uint32]string, appFields map[uint32][]CompositeField) error {
+	var names []string
+	for name := range g.variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	switch g.opts.Conflict {
+	case ConflictMerge:
+		for _, name := range names {
+			g.parsed[name] = mergeVariants(g.variants[name])
+		}
+	case ConflictNamespace:
+		g.namespaceVariants(names, appPrefixes, appFields)
+	case ConflictFail:
+		var report []string
+		for _, name := range names {
+			v := g.variants[name]
+			if apps := v.distinct(); len(apps) > 1 {
+				report = append(report, conflictReport(name, v, apps, appNames))
+				continue
+			}
+			g.parsed[name] = v.byApp[v.apps[0]]
+		}
+		if len(report) > 0 {
+			return fmt.Errorf("%d types differ between applications\n%s", len(report), strings.Join(report, "\n"))
+		}
+	default:
+		return fmt.Errorf("unknown conflict strategy %q", g.opts.Conflict)
+	}
+	return nil
+}
+
+// mergeVariants returns the union of the fields of all variants. Fields keep
+// the order of the first variant defining them, so numbering is stable. A
+// field defined differently by variants is made repeated and optional if any
+// of them is.
+func mergeVariants(v *typeVariants) CompositeField {
+	apps := v.distinct()
+	first := v.byApp[apps[0]]
+	if len(apps) == 1 {
+		return first
+	}
+	log.Printf("*** Type %s has mismatching fields, merging %d variants", first.Name, len(apps))
+	merged := first
+	merged.Fields = nil
+	index := make(map[string]int)
+	for _, appId := range apps {
+		for _, f := range v.byApp[appId].Fields {
+			key := fieldKey(f)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged.Fields)
+				merged.Fields = append(merged.Fields, f)
+				continue
+			}
+			existing, ok1 := merged.Fields[i].(*GeneralField)
+			field, ok2 := f.(*GeneralField)
+			if !ok1 || !ok2 {
+				continue
+			}
+			if (field.Repeated && !existing.Repeated) || (!field.Required && existing.Required && !existing.Repeated) {
+				merged.Fields[i] = field
+			}
+			// the merged field accepts the occurrences of every variant
+			if chosen := merged.Fields[i].(*GeneralField); chosen.Min != field.Min || chosen.Max != field.Max {
+				loosened := *chosen
+				if field.Min < loosened.Min {
+					loosened.Min = field.Min
+				}
+				if field.Max == 0 || existing.Max == 0 {
+					loosened.Max = 0
+				} else if field.Max > loosened.Max {
+					loosened.Max = field.Max
+				}
+				merged.Fields[i] = &loosened
+			}
+		}
+	}
+	return merged
+}
+
+// fieldKey identifies a field across variants: AVP code and vendor for
+// message fields, name and code for enum values.
+func fieldKey(f Field) string {
+	if field, ok := f.(*GeneralField); ok {
+		return fmt.Sprintf("%d/%d", field.AvpCode, field.VendorId)
+	}
+	return fieldSignature(f)
+}
+
+// namespaceVariants prefixes every type having more than one variant with
+// the application name, e.g. GxQoSInformation or GX_ONLINE_ENABLE_ONLINE for
+// enum values, and rewrites the references
+// of each application accordingly. Types referring to namespaced types then
+// differ as well, so this is repeated until no new conflict appears.
+func (g *Generator) namespaceVariants(names []string, appPrefixes map[uint32]string, appFields map[uint32][]CompositeField) {
+	namespaced := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, name := range names {
+			if !namespaced[name] && len(g.variants[name].distinct()) > 1 {
+				log.Printf("*** Type %s has mismatching fields, namespacing it per application", name)
+				namespaced[name] = true
+				changed = true
+			}
+		}
+		rename := func(appId uint32, fields []Field) {
+			for _, f := range fields {
+				if field, ok := f.(*GeneralField); ok && namespaced[field.DataType] {
+					field.DataType = appPrefixes[appId] + field.DataType
+				}
+			}
+		}
+		for appId, messages := range appFields {
+			for _, m := range messages {
+				rename(appId, m.Fields)
+			}
+		}
+		for _, name := range names {
+			for appId, m := range g.variants[name].byApp {
+				rename(appId, m.Fields)
+			}
+		}
+	}
+
+	for _, name := range names {
+		v := g.variants[name]
+		if !namespaced[name] {
+			g.parsed[name] = v.byApp[v.apps[0]]
+			continue
+		}
+		for _, appId := range v.apps {
+			m := v.byApp[appId]
+			m.Name = appPrefixes[appId] + name
+			if m.ProtoDataType == "enum" {
+				// enum values share the package scope, prefix them as well
+				prefix := strings.ToUpper(appPrefixes[appId]) + "_"
+				fields := make([]Field, len(m.Fields))
+				for i, f := range m.Fields {
+					value := *f.(*EnumField)
+					value.Name = prefix + value.Name
+					fields[i] = &value
+				}
+				m.Fields = fields
+			}
+			g.parsed[m.Name] = m
+		}
+	}
+}
+
+// conflictReport describes how the variants of a type differ from the
+// variant built by the first application, with the dictionary files the
+// AVPs were loaded from.
+func conflictReport(name string, v *typeVariants, apps []uint32, appNames map[uint32]string) string {
+	var b strings.Builder
+	base := v.byApp[apps[0]]
+	fmt.Fprintf(&b, "Type %s:\n", name)
+	for _, appId := range apps {
+		fmt.Fprintf(&b, "\t%s: defined in %s\n", appNames[appId], v.byApp[appId].Source)
+	}
+	baseFields := make(map[string]Field)
+	for _, f := range base.Fields {
+		baseFields[fieldKey(f)] = f
+	}
+	for _, appId := range apps[1:] {
+		other := v.byApp[appId]
+		otherFields := make(map[string]Field)
+		for _, f := range other.Fields {
+			key := fieldKey(f)
+			otherFields[key] = f
+			baseField, ok := baseFields[key]
+			if !ok {
+				fmt.Fprintf(&b, "\t\t+ %s only in %s%s\n", fieldSignature(f), appNames[appId], fieldSource(f))
+			} else if fieldSignature(baseField) != fieldSignature(f) {
+				fmt.Fprintf(&b, "\t\t~ %s in %s%s\n", fieldSignature(baseField), appNames[apps[0]], fieldSource(baseField))
+				fmt.Fprintf(&b, "\t\t  %s in %s%s\n", fieldSignature(f), appNames[appId], fieldSource(f))
+			}
+		}
+		for _, f := range base.Fields {
+			if _, ok := otherFields[fieldKey(f)]; !ok {
+				fmt.Fprintf(&b, "\t\t- %s missing in %s, only in %s%s\n", fieldSignature(f), appNames[appId], appNames[apps[0]], fieldSource(f))
+			}
+		}
+	}
+	return b.String()
+}
+
+func fieldSource(f Field) string {
+	if field, ok := f.(*GeneralField); ok && field.Source != "" {
+		return " (" + field.Source + ")"
+	}
+	return ""
+}
@@ -0,0 +1,686 @@
+package diamproto
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+)
+
+const (
+	diamImport        = "github.com/fiorix/go-diameter/v4/diam"
+	avpImport         = "github.com/fiorix/go-diameter/v4/diam/avp"
+	datatypeImport    = "github.com/fiorix/go-diameter/v4/diam/datatype"
+	dictImport        = "github.com/fiorix/go-diameter/v4/diam/dict"
+	wrapperspbImport  = "google.golang.org/protobuf/types/known/wrapperspb"
+	timestamppbImport = "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// goDataTypes maps Diameter data types to their go-diameter datatype names.
+var goDataTypes = map[datatype.TypeID]string{
+	datatype.UnknownType:          "datatype.Unknown",
+	datatype.AddressType:          "datatype.Address",
+	datatype.DiameterIdentityType: "datatype.DiameterIdentity",
+	datatype.DiameterURIType:      "datatype.DiameterURI",
+	datatype.EnumeratedType:       "datatype.Enumerated",
+	datatype.Float32Type:          "datatype.Float32",
+	datatype.Float64Type:          "datatype.Float64",
+	datatype.IPFilterRuleType:     "datatype.IPFilterRule",
+	datatype.IPv4Type:             "datatype.IPv4",
+	datatype.IPv6Type:             "datatype.IPv6",
+	datatype.Integer32Type:        "datatype.Integer32",
+	datatype.Integer64Type:        "datatype.Integer64",
+	datatype.OctetStringType:      "datatype.OctetString",
+	datatype.QoSFilterRuleType:    "datatype.QoSFilterRule",
+	datatype.TimeType:             "datatype.Time",
+	datatype.UTF8StringType:       "datatype.UTF8String",
+	datatype.Unsigned32Type:       "datatype.Unsigned32",
+	datatype.Unsigned64Type:       "datatype.Unsigned64",
+}
+
+// goScalarTypes maps proto scalar types to the Go types generated by protoc-gen-go.
+var goScalarTypes = map[string]string{
+	"string": "string",
+	"bytes":  "[]byte",
+	"bool":   "bool",
+	"uint32": "uint32",
+	"uint64": "uint64",
+	"int32":  "int32",
+	"int64":  "int64",
+	"float":  "float32",
+	"double": "float64",
+}
+
+// wrapperScalarTypes maps wrapper types to the scalar they wrap and to the
+// wrapperspb constructor.
+var wrapperScalarTypes = map[string][2]string{
+	"google.protobuf.DoubleValue": {"double", "wrapperspb.Double"},
+	"google.protobuf.FloatValue":  {"float", "wrapperspb.Float"},
+	"google.protobuf.Int64Value":  {"int64", "wrapperspb.Int64"},
+	"google.protobuf.UInt64Value": {"uint64", "wrapperspb.UInt64"},
+	"google.protobuf.Int32Value":  {"int32", "wrapperspb.Int32"},
+	"google.protobuf.UInt32Value": {"uint32", "wrapperspb.UInt32"},
+	"google.protobuf.BoolValue":   {"bool", "wrapperspb.Bool"},
+	"google.protobuf.StringValue": {"string", "wrapperspb.String"},
+	"google.protobuf.BytesValue":  {"bytes", "wrapperspb.Bytes"},
+}
+
+// ConverterFile is the Go counterpart of a ProtoFile, holding FromDiameter
+// and ToDiameter functions for each of its messages.
+type ConverterFile struct {
+	proto   *ProtoFile
+	imports map[string]bool
+	body    strings.Builder
+	// diameter/options.proto, declaring RawAvp and DiameterHeader
+	options *ProtoFile
+	// whether the DiameterHeader conversions used by envelopes are needed
+	envelopes bool
+}
+
+// goImportPath returns the Go import path of a go_package value.
+func goImportPath(goPackage string) string {
+	if i := strings.LastIndex(goPackage, ";"); i >= 0 {
+		return goPackage[:i]
+	}
+	return goPackage
+}
+
+// goPackageName returns the Go package name declared by a go_package value.
+func goPackageName(goPackage string) string {
+	if i := strings.LastIndex(goPackage, ";"); i >= 0 {
+		return goPackage[i+1:]
+	}
+	name := goPackage[strings.LastIndex(goPackage, "/")+1:]
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// goCamelCase converts a proto identifier into the Go identifier generated by
+// protoc-gen-go.
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+		case isASCIIDigit(c):
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func newConverterFile(proto, options *ProtoFile) *ConverterFile {
+	return &ConverterFile{proto: proto, options: options, imports: make(map[string]bool)}
+}
+
+// diameterType returns the Go name of a message declared by
+// diameter/options.proto, importing its package unless it is the package of
+// the file.
+func (c *ConverterFile) diameterType(name string) string {
+	goPackage := c.options.goPackageOrDefault()
+	if goImportPath(goPackage) == goImportPath(c.proto.goPackageOrDefault()) {
+		return name
+	}
+	c.imports[goImportPath(goPackage)] = true
+	return goPackageName(goPackage) + "." + name
+}
+
+// Bytes renders the gofmt'ed Go source of the file.
+func (c *ConverterFile) Bytes() ([]byte, error) {
+	for _, m := range c.proto.Messages {
+		if m.ProtoDataType == "message" {
+			c.message(m)
+		}
+	}
+	for _, e := range c.proto.Envelopes {
+		c.envelope(e)
+	}
+	if c.proto.Name == "common.proto" {
+		c.helpers()
+		if c.envelopes {
+			c.headerHelpers()
+		}
+	}
+
+	var std, imports []string
+	for path := range c.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			imports = append(imports, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(imports)
+	if len(std) > 0 && len(imports) > 0 {
+		std = append(std, "")
+	}
+	imports = append(std, imports...)
+
+	var b strings.Builder
+	b.WriteString("// Code generated by diam-to-proto. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", goPackageName(c.proto.goPackageOrDefault()))
+	if len(imports) > 0 {
+		b.WriteString("import (\n")
+		for _, path := range imports {
+			if path == "" {
+				b.WriteString("\n")
+				continue
+			}
+			if name := c.importName(path); name != "" {
+				fmt.Fprintf(&b, "\t%s %q\n", name, path)
+				continue
+			}
+			fmt.Fprintf(&b, "\t%q\n", path)
+		}
+		b.WriteString(")\n\n")
+	}
+	b.WriteString(c.body.String())
+	return format.Source([]byte(b.String()))
+}
+
+// importName returns the name an import is given, when the package name
+// declared by the go_package option of diameter/options.proto differs from
+// the last element of its import path.
+func (c *ConverterFile) importName(path string) string {
+	goPackage := c.options.goPackageOrDefault()
+	if path != goImportPath(goPackage) || strings.HasSuffix(path, "/"+goPackageName(goPackage)) {
+		return ""
+	}
+	return goPackageName(goPackage)
+}
+
+// Name returns the name of the Go file, derived from the proto file so that
+// it sits next to the protoc-gen-go output.
+func (c *ConverterFile) Name() string {
+	return strings.TrimSuffix(c.proto.Name, ".proto") + "_diam.go"
+}
+
+func (c *ConverterFile) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&c.body, format, args...)
+}
+
+func (c *ConverterFile) helpers() {
+	c.imports[diamImport] = true
+	c.imports[dictImport] = true
+	c.imports["fmt"] = true
+	c.printf("// Dictionary is used to build the Diameter messages returned by ToDiameter.\n")
+	c.printf("var Dictionary = dict.Default\n\n")
+	c.printf("func unexpectedType(a *diam.AVP) error {\n")
+	c.printf("return fmt.Errorf(\"AVP %%d (vendor %%d): unexpected data type %%T\", a.Code, a.VendorID, a.Data)\n")
+	c.printf("}\n\n")
+	c.printf("func conflictingAlternative(a *diam.AVP) error {\n")
+	c.printf("return fmt.Errorf(\"AVP %%d (vendor %%d): an alternative AVP was already decoded\", a.Code, a.VendorID)\n")
+	c.printf("}\n")
+}
+
+// headerHelpers writes the conversions between go-diameter headers and the
+// DiameterHeader of envelopes.
+func (c *ConverterFile) headerHelpers() {
+	c.imports[avpImport] = true
+	c.imports[datatypeImport] = true
+	header := c.diameterType("DiameterHeader")
+	c.printf("\n// newDiameterHeader returns the header of msg, along with its Session-Id.\n")
+	c.printf("func newDiameterHeader(msg *diam.Message) *%s {\n", header)
+	c.printf("flags := msg.Header.CommandFlags\n")
+	c.printf("h := &%s{\n", header)
+	c.printf("ApplicationId: msg.Header.ApplicationID,\n")
+	c.printf("CommandCode: msg.Header.CommandCode,\n")
+	c.printf("Request: flags&diam.RequestFlag != 0,\n")
+	c.printf("Proxiable: flags&diam.ProxiableFlag != 0,\n")
+	c.printf("Error: flags&diam.ErrorFlag != 0,\n")
+	c.printf("Retransmitted: flags&diam.RetransmittedFlag != 0,\n")
+	c.printf("HopByHopId: msg.Header.HopByHopID,\n")
+	c.printf("EndToEndId: msg.Header.EndToEndID,\n")
+	c.printf("}\n")
+	c.printf("for _, a := range msg.AVP {\n")
+	c.printf("if v, ok := a.Data.(datatype.UTF8String); ok && a.Code == avp.SessionID && a.VendorID == 0 {\n")
+	c.printf("h.SessionId = string(v)\n")
+	c.printf("}\n")
+	c.printf("}\n")
+	c.printf("return h\n")
+	c.printf("}\n\n")
+	c.printf("// setDiameterHeader sets the identifiers and the P, E and T flags of msg\n")
+	c.printf("// from h, the other fields following from the message.\n")
+	c.printf("func setDiameterHeader(msg *diam.Message, h *%s) {\n", header)
+	c.printf("msg.Header.HopByHopID = h.GetHopByHopId()\n")
+	c.printf("msg.Header.EndToEndID = h.GetEndToEndId()\n")
+	for _, flag := range []string{"Proxiable", "Error", "Retransmitted"} {
+		c.printf("if h.Get%s() {\nmsg.Header.CommandFlags |= diam.%sFlag\n}\n", flag, flag)
+	}
+	c.printf("}\n")
+}
+
+// envelope writes the conversions of an envelope, whose body is the request
+// or the answer depending on the R flag.
+func (c *ConverterFile) envelope(e Envelope) {
+	c.imports[diamImport] = true
+	c.imports["fmt"] = true
+	name := goCamelCase(e.Name)
+	c.printf("// FromDiameter fills m with the header of msg, and with the request or the\n")
+	c.printf("// answer holding its AVPs depending on the R flag.\n")
+	c.printf("func (m *%s) FromDiameter(msg *diam.Message) error {\n", name)
+	c.printf("m.Header = newDiameterHeader(msg)\n")
+	c.printf("if m.Header.Request {\n")
+	c.printf("body := &%s{}\n", goCamelCase(e.Request))
+	c.printf("if err := body.FromDiameter(msg); err != nil {\nreturn err\n}\n")
+	c.printf("m.Body = &%s_Request{Request: body}\n", name)
+	c.printf("return nil\n")
+	c.printf("}\n")
+	c.printf("body := &%s{}\n", goCamelCase(e.Answer))
+	c.printf("if err := body.FromDiameter(msg); err != nil {\nreturn err\n}\n")
+	c.printf("m.Body = &%s_Answer{Answer: body}\n", name)
+	c.printf("return nil\n")
+	c.printf("}\n\n")
+	c.printf("// ToDiameter builds the Diameter message of the body of m, with the\n")
+	c.printf("// identifiers and flags of its header.\n")
+	c.printf("func (m *%s) ToDiameter() (*diam.Message, error) {\n", name)
+	c.printf("var msg *diam.Message\n")
+	c.printf("var err error\n")
+	c.printf("if request := m.GetRequest(); request != nil {\n")
+	c.printf("msg, err = request.ToDiameter()\n")
+	c.printf("} else if answer := m.GetAnswer(); answer != nil {\n")
+	c.printf("msg, err = answer.ToDiameter()\n")
+	c.printf("} else {\n")
+	c.printf("return nil, fmt.Errorf(%q)\n", e.Name+": missing body")
+	c.printf("}\n")
+	c.printf("if err != nil {\nreturn nil, err\n}\n")
+	c.printf("setDiameterHeader(msg, m.Header)\n")
+	c.printf("return msg, nil\n")
+	c.printf("}\n\n")
+}
+
+func (c *ConverterFile) message(m CompositeField) {
+	c.imports[diamImport] = true
+	name := goCamelCase(m.Name)
+	if m.CommandCode != 0 {
+		flags := "0"
+		if m.Request {
+			flags = "diam.RequestFlag"
+		}
+		c.printf("// FromDiameter fills m with the AVPs of msg.\n")
+		c.printf("func (m *%s) FromDiameter(msg *diam.Message) error {\n", name)
+		c.printf("return m.fromAVPs(msg.AVP)\n")
+		c.printf("}\n\n")
+		c.printf("// ToDiameter builds the Diameter message represented by m.\n")
+		c.printf("func (m *%s) ToDiameter() (*diam.Message, error) {\n", name)
+		c.printf("avps, err := m.toAVPs()\n")
+		c.printf("if err != nil {\nreturn nil, err\n}\n")
+		c.printf("msg := diam.NewMessage(%d, %s, %d, 0, 0, Dictionary)\n", m.CommandCode, flags, m.AppId)
+		c.printf("for _, a := range avps {\nmsg.AddAVP(a)\n}\n")
+		c.printf("return msg, nil\n")
+		c.printf("}\n\n")
+	} else {
+		c.printf("// FromDiameter fills m with the AVPs grouped in a.\n")
+		c.printf("func (m *%s) FromDiameter(a *diam.AVP) error {\n", name)
+		c.printf("g, ok := a.Data.(*diam.GroupedAVP)\n")
+		c.printf("if !ok {\nreturn unexpectedType(a)\n}\n")
+		c.printf("return m.fromAVPs(g.AVP)\n")
+		c.printf("}\n\n")
+		c.printf("// ToDiameter builds the grouped AVP data represented by m.\n")
+		c.printf("func (m *%s) ToDiameter() (*diam.GroupedAVP, error) {\n", name)
+		c.printf("avps, err := m.toAVPs()\n")
+		c.printf("if err != nil {\nreturn nil, err\n}\n")
+		c.printf("return &diam.GroupedAVP{AVP: avps}, nil\n")
+		c.printf("}\n\n")
+	}
+
+	var fields []*GeneralField
+	for _, f := range m.Fields {
+		if field, ok := f.(*GeneralField); ok && c.supported(field) {
+			fields = append(fields, field)
+		}
+	}
+
+	c.printf("func (m *%s) fromAVPs(avps []*diam.AVP) error {\n", name)
+	if len(fields) > 0 || m.UnknownAvps {
+		c.printf("for _, a := range avps {\n")
+		c.printf("switch {\n")
+		for _, field := range fields {
+			c.printf("case a.Code == %d && a.VendorID == %d:\n", field.AvpCode, field.VendorId)
+			c.decodeField(name, field)
+		}
+		if m.UnknownAvps {
+			c.printf("default:\n")
+			c.printf("m.UnknownAvps = append(m.UnknownAvps, &%s{Code: a.Code, VendorId: a.VendorID, Flags: uint32(a.Flags), Data: a.Data.Serialize()})\n",
+				c.diameterType("RawAvp"))
+		}
+		c.printf("}\n")
+		c.printf("}\n")
+	}
+	c.printf("return nil\n")
+	c.printf("}\n\n")
+
+	c.printf("func (m *%s) toAVPs() ([]*diam.AVP, error) {\n", name)
+	c.printf("var avps []*diam.AVP\n")
+	for _, field := range fields {
+		c.encodeField(name, field)
+	}
+	if m.UnknownAvps {
+		c.imports[datatypeImport] = true
+		c.printf("for _, x := range m.UnknownAvps {\n")
+		c.printf("avps = append(avps, diam.NewAVP(x.Code, uint8(x.Flags), x.VendorId, datatype.Unknown(x.Data)))\n")
+		c.printf("}\n")
+	}
+	c.printf("return avps, nil\n")
+	c.printf("}\n\n")
+	c.validate(name, m)
+}
+
+// validate writes the Validate method of a message, checking the number of
+// occurrences of its AVPs against the bounds of the dictionary rules. The
+// presence of required AVPs is checked for fields able to represent an absent
+// AVP, i.e. messages, wrappers and optional scalars, and for strings and bytes
+// which must not be empty. Required numbers and enums, for which zero is a
+// valid value, are not checked.
+func (c *ConverterFile) validate(name string, m CompositeField) {
+	// check prints a check returning an error, which needs fmt
+	check := func(format string, args ...interface{}) {
+		c.imports["fmt"] = true
+		c.printf(format, args...)
+	}
+	c.printf("// Validate checks that the AVPs of m, and of the grouped AVPs it holds,\n")
+	c.printf("// occur as many times as the dictionary rules allow.\n")
+	c.printf("func (m *%s) Validate() error {\n", name)
+	oneofs := make(map[string]bool)
+	for _, f := range m.Fields {
+		field, ok := f.(*GeneralField)
+		if !ok {
+			continue
+		}
+		if field.Oneof != "" {
+			// members are checked together where the first one appears
+			if !oneofs[field.Oneof] {
+				oneofs[field.Oneof] = true
+				c.validateOneof(name, m, field.Oneof, check)
+			}
+			continue
+		}
+		x := "m." + goCamelCase(field.VarName)
+		grouped := field.AvpType == datatype.GroupedType
+		if field.Repeated {
+			if min := field.MinOccurs(); min > 0 {
+				check("if len(%s) < %d {\nreturn fmt.Errorf(%q, len(%s))\n}\n", x, min,
+					fmt.Sprintf("AVP %s occurs %%d times, at least %d expected", field.JsonFieldName, min), x)
+			}
+			if field.Max > 1 {
+				check("if len(%s) > %d {\nreturn fmt.Errorf(%q, len(%s))\n}\n", x, field.Max,
+					fmt.Sprintf("AVP %s occurs %%d times, at most %d expected", field.JsonFieldName, field.Max), x)
+			}
+			if grouped {
+				c.printf("for _, x := range %s {\n", x)
+				check("if err := x.Validate(); err != nil {\nreturn fmt.Errorf(%q, err)\n}\n", field.JsonFieldName+": %w")
+				c.printf("}\n")
+			}
+			continue
+		}
+		required := field.Required && (isMessageType(field) || field.Optional)
+		missing := ""
+		switch {
+		case required:
+			missing = x + " == nil"
+		case field.Required && field.DataType == "string":
+			// proto3 cannot tell an absent scalar from its zero value, and
+			// required strings and octets are never legitimately empty
+			missing = x + ` == ""`
+		case field.Required && field.DataType == "bytes":
+			missing = "len(" + x + ") == 0"
+		}
+		if missing != "" {
+			check("if %s {\nreturn fmt.Errorf(%q)\n}\n", missing, "missing required AVP "+field.JsonFieldName)
+		}
+		if grouped {
+			if !required {
+				c.printf("if %s != nil {\n", x)
+			}
+			check("if err := %s.Validate(); err != nil {\nreturn fmt.Errorf(%q, err)\n}\n", x, field.JsonFieldName+": %w")
+			if !required {
+				c.printf("}\n")
+			}
+		}
+	}
+	c.printf("return nil\n")
+	c.printf("}\n\n")
+}
+
+// validateOneof writes the checks of a oneof of m: a required oneof must be
+// set, and a grouped member is validated when it is the one chosen.
+func (c *ConverterFile) validateOneof(name string, m CompositeField, oneof string, check func(string, ...interface{})) {
+	x := "m." + goCamelCase(oneof)
+	var members []*GeneralField
+	required := false
+	for _, f := range m.Fields {
+		if field, ok := f.(*GeneralField); ok && field.Oneof == oneof {
+			members = append(members, field)
+			required = required || field.OneofRequired
+		}
+	}
+	if required {
+		var names []string
+		for _, field := range members {
+			names = append(names, field.JsonFieldName)
+		}
+		check("if %s == nil {\nreturn fmt.Errorf(%q)\n}\n", x, "missing required AVP, one of "+strings.Join(names, ", "))
+	}
+	for _, field := range members {
+		if field.AvpType != datatype.GroupedType {
+			continue
+		}
+		v := "x." + goCamelCase(field.VarName)
+		c.printf("if x, ok := %s.(*%s); ok && %s != nil {\n", x, oneofWrapper(name, field), v)
+		check("if err := %s.Validate(); err != nil {\nreturn fmt.Errorf(%q, err)\n}\n", v, field.JsonFieldName+": %w")
+		c.printf("}\n")
+	}
+}
+
+// supported reports whether a conversion can be generated for the field.
+func (c *ConverterFile) supported(f *GeneralField) bool {
+	switch {
+	case f.AvpType == datatype.GroupedType, isEnum(f):
+		return true
+	case f.DataType == "google.protobuf.Timestamp":
+		return f.AvpType == datatype.TimeType
+	}
+	if _, ok := goDataTypes[f.AvpType]; !ok || f.AvpType == datatype.TimeType {
+		return false
+	}
+	if _, ok := wrapperScalarTypes[f.DataType]; ok {
+		return true
+	}
+	_, ok := goScalarTypes[f.DataType]
+	return ok
+}
+
+// isEnum reports whether the field holds a generated enum, rather than a
+// scalar set by an Override.
+func isEnum(f *GeneralField) bool {
+	if f.AvpType != datatype.EnumeratedType {
+		return false
+	}
+	_, scalar := goScalarTypes[f.DataType]
+	_, wrapper := wrapperScalarTypes[f.DataType]
+	return !scalar && !wrapper
+}
+
+// scalarType returns the proto scalar type carried by the field, unwrapping
+// wrapper types.
+func scalarType(f *GeneralField) string {
+	if w, ok := wrapperScalarTypes[f.DataType]; ok {
+		return w[0]
+	}
+	return f.DataType
+}
+
+func isIPType(t datatype.TypeID) bool {
+	return t == datatype.AddressType || t == datatype.IPv4Type || t == datatype.IPv6Type
+}
+
+// decodeValue returns the expression converting the datatype value v into
+// the Go representation of the field.
+func (c *ConverterFile) decodeValue(f *GeneralField) string {
+	switch {
+	case f.DataType == "google.protobuf.Timestamp":
+		c.imports[timestamppbImport] = true
+		c.imports["time"] = true
+		return "timestamppb.New(time.Time(v))"
+	case isEnum(f):
+		return fmt.Sprintf("%s(v)", goCamelCase(f.DataType))
+	}
+	scalar := scalarType(f)
+	var value string
+	switch {
+	case scalar == "string" && isIPType(f.AvpType):
+		c.imports["net"] = true
+		value = "net.IP(v).String()"
+	default:
+		value = fmt.Sprintf("%s(v)", goScalarTypes[scalar])
+	}
+	if w, ok := wrapperScalarTypes[f.DataType]; ok {
+		c.imports[wrapperspbImport] = true
+		value = fmt.Sprintf("%s(%s)", w[1], value)
+	}
+	return value
+}
+
+// encodeValue returns the expression converting the Go value x of the field
+// into its go-diameter datatype.
+func (c *ConverterFile) encodeValue(f *GeneralField, x string) string {
+	c.imports[datatypeImport] = true
+	typ := goDataTypes[f.AvpType]
+	switch {
+	case f.DataType == "google.protobuf.Timestamp":
+		return fmt.Sprintf("datatype.Time(%s.AsTime())", x)
+	}
+	if _, ok := wrapperScalarTypes[f.DataType]; ok {
+		x += ".GetValue()"
+	} else if f.Optional {
+		x = "*" + x
+	}
+	if scalarType(f) == "string" && isIPType(f.AvpType) {
+		c.imports["net"] = true
+		return fmt.Sprintf("%s(net.ParseIP(%s))", typ, x)
+	}
+	return fmt.Sprintf("%s(%s)", typ, x)
+}
+
+// oneofWrapper returns the type protoc-gen-go generates to hold the oneof
+// member f of the message owner.
+func oneofWrapper(owner string, f *GeneralField) string {
+	return owner + "_" + goCamelCase(f.VarName)
+}
+
+func (c *ConverterFile) decodeField(owner string, f *GeneralField) {
+	target := "m." + goCamelCase(f.VarName)
+	assign := func(value string) {
+		switch {
+		case f.Oneof != "":
+			// a single alternative is accepted
+			oneof := "m." + goCamelCase(f.Oneof)
+			c.printf("if %s != nil {\nreturn conflictingAlternative(a)\n}\n", oneof)
+			c.printf("%s = &%s{%s: %s}\n", oneof, oneofWrapper(owner, f), goCamelCase(f.VarName), value)
+		case f.Repeated:
+			c.printf("%s = append(%s, %s)\n", target, target, value)
+		default:
+			c.printf("%s = %s\n", target, value)
+		}
+	}
+	if f.AvpType == datatype.GroupedType {
+		c.printf("x := &%s{}\n", goCamelCase(f.DataType))
+		c.printf("if err := x.FromDiameter(a); err != nil {\nreturn err\n}\n")
+		assign("x")
+		return
+	}
+	c.imports[datatypeImport] = true
+	c.printf("v, ok := a.Data.(%s)\n", goDataTypes[f.AvpType])
+	c.printf("if !ok {\nreturn unexpectedType(a)\n}\n")
+	if f.Optional {
+		c.printf("x := %s\n", c.decodeValue(f))
+		assign("&x")
+		return
+	}
+	assign(c.decodeValue(f))
+}
+
+func (c *ConverterFile) encodeField(owner string, f *GeneralField) {
+	source := "m." + goCamelCase(f.VarName)
+	x := source
+	if f.Oneof != "" {
+		x = "x." + goCamelCase(f.VarName)
+		check := "ok"
+		if isMessageType(f) {
+			check += " && " + x + " != nil"
+		}
+		c.printf("if x, ok := m.%s.(*%s); %s {\n", goCamelCase(f.Oneof), oneofWrapper(owner, f), check)
+	} else if f.Repeated {
+		x = "x"
+		c.printf("for _, x := range %s {\n", source)
+	} else if check := presenceCheck(f, source); check != "" {
+		c.printf("if %s {\n", check)
+	} else if f.AvpType == datatype.GroupedType {
+		c.printf("{\n")
+	} else {
+		defer c.printf("\n")
+	}
+
+	c.imports[avpImport] = true
+	flags := "0"
+	if f.Mandatory {
+		flags = "avp.Mbit"
+	}
+	if f.AvpType == datatype.GroupedType {
+		c.printf("g, err := %s.ToDiameter()\n", x)
+		c.printf("if err != nil {\nreturn nil, err\n}\n")
+		c.printf("avps = append(avps, diam.NewAVP(%d, %s, %d, g))\n", f.AvpCode, flags, f.VendorId)
+	} else {
+		c.printf("avps = append(avps, diam.NewAVP(%d, %s, %d, %s))\n", f.AvpCode, flags, f.VendorId, c.encodeValue(f, x))
+	}
+	if f.Oneof != "" || f.Repeated || f.AvpType == datatype.GroupedType || presenceCheck(f, source) != "" {
+		c.printf("}\n")
+	}
+}
+
+// presenceCheck returns the condition under which a singular field is
+// encoded, or an empty string if it is always encoded.
+func presenceCheck(f *GeneralField, x string) string {
+	if isMessageType(f) || f.Optional {
+		return x + " != nil"
+	}
+	if f.Required {
+		return ""
+	}
+	switch f.DataType {
+	case "string":
+		return x + ` != ""`
+	case "bytes":
+		return "len(" + x + ") > 0"
+	case "bool":
+		return x
+	}
+	return x + " != 0"
+}
+
+// isMessageType reports whether the Go field is a pointer to a message.
+func isMessageType(f *GeneralField) bool {
+	_, wrapper := wrapperScalarTypes[f.DataType]
+	return wrapper || f.AvpType == datatype.GroupedType || f.DataType == "google.protobuf.Timestamp"
+}
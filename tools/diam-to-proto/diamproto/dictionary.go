@@ -0,0 +1,148 @@
+package diamproto
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+)
+
+// Dictionary holds the loaded Diameter dictionaries along with the file each
+// application and AVP was loaded from.
+type Dictionary struct {
+	P *dict.Parser
+	// dictionary file each application and AVP was loaded from
+	appSources map[*dict.App]string
+	sources    map[*dict.AVP]string
+}
+
+// LoadDictionary returns the dictionaries loaded from the given folders.
+func LoadDictionary(paths ...string) (*Dictionary, error) {
+	d := &Dictionary{}
+	if err := d.Load(paths...); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Load loads the dictionary files found below each folder, in the order
+// given. Later definitions of an application extend the earlier ones.
+func (d *Dictionary) Load(paths ...string) error {
+	if d.P == nil {
+		d.P, _ = dict.NewParser()
+	}
+	if d.sources == nil {
+		d.appSources = make(map[*dict.App]string)
+		d.sources = make(map[*dict.AVP]string)
+	}
+	loaded := len(d.P.Apps())
+	for _, path := range paths {
+		log.Printf("Loading dictionaries from %s", path)
+		err := filepath.WalkDir(path, func(path string, info fs.DirEntry, err error) error {
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			dictErr := d.P.LoadFile(path)
+			if dictErr != nil {
+				log.Printf("Failed to load dictionary: %s: %s", path, dictErr)
+				return dictErr
+			}
+			apps := d.P.Apps()
+			for _, app := range apps[loaded:] {
+				d.appSources[app] = path
+				for _, avp := range app.AVP {
+					d.sources[avp] = path
+				}
+			}
+			loaded = len(apps)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppSource returns the dictionary file the application was loaded from.
+func (d *Dictionary) AppSource(app *dict.App) string {
+	return d.appSources[app]
+}
+
+// Source returns the dictionary file the AVP was loaded from.
+func (d *Dictionary) Source(avp *dict.AVP) string {
+	return d.sources[avp]
+}
+
+func (d *Dictionary) search(appId, vendorId uint32, code interface{}) (*dict.AVP, error) {
+	avp, level, err := d.lookup(appId, vendorId, code)
+	var message string
+	switch level {
+	case foundWithVendor:
+		message = "+ Found AVP with VendorId [%s]"
+	case foundWithoutVendor:
+		message = "- Failed to find AVP with VendorId [ %s ]"
+	case foundGlobally:
+		message = "-- Failed to find AVP without VendorId [ %s ]"
+	default:
+		message = "--- Failed to find AVP globally [ %s ]"
+	}
+	log.Printf(message, code)
+	return avp, err
+}
+
+// Stages of lookup at which an AVP was found.
+const (
+	foundWithVendor = iota
+	foundWithoutVendor
+	foundGlobally
+	notFound
+)
+
+// lookup searches an AVP in the application with the vendor, then without
+// the vendor, and finally in every loaded application.
+func (d *Dictionary) lookup(appId, vendorId uint32, code interface{}) (*dict.AVP, int, error) {
+	avp, err := d.P.FindAVPWithVendor(appId, code, vendorId)
+	if err == nil {
+		return avp, foundWithVendor, nil
+	}
+	avp, err = d.P.FindAVP(appId, code)
+	if err == nil {
+		return avp, foundWithoutVendor, nil
+	}
+	if avp = d.scan(code); avp != nil {
+		return avp, foundGlobally, nil
+	}
+	return nil, notFound, fmt.Errorf("could not find AVP %v", code)
+}
+
+// scan searches an AVP by name or code in every loaded application, in load
+// order. Unlike dict.Parser.ScanAVP, which iterates over a map, the first
+// definition loaded always wins.
+func (d *Dictionary) scan(code interface{}) *dict.AVP {
+	for _, app := range d.P.Apps() {
+		for _, avp := range app.AVP {
+			switch c := code.(type) {
+			case string:
+				if avp.Name == c {
+					return avp
+				}
+			case uint32:
+				if avp.Code == c {
+					return avp
+				}
+			case int:
+				if avp.Code == uint32(c) {
+					return avp
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package diamproto
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+)
+
+// DiffChange is a difference between two sets of dictionaries. Breaking
+// changes alter the wire format or the API of the generated protobufs.
+type DiffChange struct {
+	App      string `json:"app"`
+	Message  string `json:"message"`
+	Breaking bool   `json:"breaking"`
+}
+
+func (c DiffChange) String() string {
+	kind := "compatible"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%-10s %s: %s", kind, c.App, c.Message)
+}
+
+type avpKey struct {
+	code, vendorId uint32
+}
+
+// appIndex gathers the commands and AVPs of every application loaded with
+// the same ID, in load order.
+type appIndex struct {
+	id       uint32
+	name     string
+	vendorId uint32
+	avps     map[avpKey]*dict.AVP
+	avpKeys  []avpKey
+	commands map[uint32]*dict.Command
+	codes    []uint32
+}
+
+func indexApps(d *Dictionary) map[uint32]*appIndex {
+	index := make(map[uint32]*appIndex)
+	for _, app := range d.P.Apps() {
+		idx, ok := index[app.ID]
+		if !ok {
+			idx = &appIndex{
+				id:       app.ID,
+				name:     fmt.Sprintf("%s (%d)", app.Name, app.ID),
+				vendorId: uint32(dict.UndefinedVendorID),
+				avps:     make(map[avpKey]*dict.AVP),
+				commands: make(map[uint32]*dict.Command),
+			}
+			index[app.ID] = idx
+		}
+		if len(app.Vendor) > 0 && idx.vendorId == uint32(dict.UndefinedVendorID) {
+			idx.vendorId = app.Vendor[0].ID
+		}
+		for _, avp := range app.AVP {
+			key := avpKey{avp.Code, avp.VendorID}
+			if _, ok := idx.avps[key]; !ok {
+				idx.avps[key] = avp
+				idx.avpKeys = append(idx.avpKeys, key)
+			}
+		}
+		for _, command := range app.Command {
+			if _, ok := idx.commands[command.Code]; !ok {
+				idx.commands[command.Code] = command
+				idx.codes = append(idx.codes, command.Code)
+			}
+		}
+	}
+	return index
+}
+
+// differ compares the applications of the old dictionaries a with those of
+// the new dictionaries b, generated with opts.
+type differ struct {
+	a, b    *Dictionary
+	opts    Options
+	types   TypeMap
+	changes []DiffChange
+}
+
+func (d *differ) report(app *appIndex, breaking bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, DiffChange{App: app.name, Message: fmt.Sprintf(format, args...), Breaking: breaking})
+}
+
+// Diff reports the applications, commands, AVPs, rules and enum
+// values added, removed or changed between a and b, for all applications or
+// only the selected ones if selected is not nil. Changes are classified for
+// protobufs generated with the NumberFormat, Lock, Optional and TypeMap of
+// opts.
+func Diff(a, b *Dictionary, selected map[uint32]bool, opts Options) []DiffChange {
+	oldApps, newApps := indexApps(a), indexApps(b)
+	ids := make(map[uint32]bool)
+	for id := range oldApps {
+		ids[id] = true
+	}
+	for id := range newApps {
+		ids[id] = true
+	}
+	var sorted []uint32
+	for id := range ids {
+		if selected == nil || selected[id] {
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	d := &differ{a: a, b: b, opts: opts, types: withDefaults(opts.TypeMap)}
+	for _, id := range sorted {
+		oldApp, newApp := oldApps[id], newApps[id]
+		switch {
+		case newApp == nil:
+			d.report(oldApp, true, "application removed")
+		case oldApp == nil:
+			d.report(newApp, false, "application added")
+		default:
+			d.diffApp(oldApp, newApp)
+		}
+	}
+	return d.changes
+}
+
+func (d *differ) diffApp(oldApp, newApp *appIndex) {
+	for _, code := range oldApp.codes {
+		oldCommand := oldApp.commands[code]
+		newCommand, ok := newApp.commands[code]
+		if !ok {
+			d.report(newApp, true, "command %s (%d) removed", oldCommand.Name, code)
+			continue
+		}
+		d.diffRules(newApp, fmt.Sprintf("command %s request", newCommand.Name), oldCommand.Request.Rule, newCommand.Request.Rule)
+		d.diffRules(newApp, fmt.Sprintf("command %s answer", newCommand.Name), oldCommand.Answer.Rule, newCommand.Answer.Rule)
+	}
+	for _, code := range newApp.codes {
+		if _, ok := oldApp.commands[code]; !ok {
+			d.report(newApp, false, "command %s (%d) added", newApp.commands[code].Name, code)
+		}
+	}
+
+	for _, key := range oldApp.avpKeys {
+		oldAVP := oldApp.avps[key]
+		newAVP, ok := newApp.avps[key]
+		if !ok {
+			d.report(newApp, true, "AVP %s (%d vendor %d) removed", oldAVP.Name, key.code, key.vendorId)
+			continue
+		}
+		d.diffAVP(newApp, oldAVP, newAVP)
+	}
+	for _, key := range newApp.avpKeys {
+		if _, ok := oldApp.avps[key]; !ok {
+			newAVP := newApp.avps[key]
+			d.report(newApp, false, "AVP %s (%d vendor %d) added", newAVP.Name, key.code, key.vendorId)
+		}
+	}
+}
+
+func (d *differ) diffAVP(newApp *appIndex, oldAVP, newAVP *dict.AVP) {
+	owner := fmt.Sprintf("AVP %s (%d)", newAVP.Name, newAVP.Code)
+	if oldAVP.Name != newAVP.Name {
+		d.report(newApp, true, "AVP code %d renamed from %s to %s", newAVP.Code, oldAVP.Name, newAVP.Name)
+	}
+	if oldAVP.Data.Type != newAVP.Data.Type {
+		d.report(newApp, !d.sameProtoType(oldAVP.Data.Type, newAVP.Data.Type), "%s type changed from %s to %s",
+			owner, oldAVP.Data.TypeName, newAVP.Data.TypeName)
+		return
+	}
+	switch newAVP.Data.Type {
+	case datatype.EnumeratedType:
+		d.diffEnum(newApp, owner, oldAVP.Data.Enum, newAVP.Data.Enum)
+	case datatype.GroupedType:
+		d.diffRules(newApp, owner, oldAVP.Data.Rule, newAVP.Data.Rule)
+	}
+}
+
+// sameProtoType reports whether two data types generate the same proto types,
+// required and not, e.g. OctetString and Address, so that switching between
+// them is compatible.
+func (d *differ) sameProtoType(a, b datatype.TypeID) bool {
+	if a == datatype.EnumeratedType || a == datatype.GroupedType || b == datatype.EnumeratedType || b == datatype.GroupedType {
+		return false
+	}
+	for _, required := range []bool{true, false} {
+		pa, ok1 := d.types.For(a, required)
+		pb, ok2 := d.types.For(b, required)
+		if !ok1 || !ok2 || pa != pb {
+			return false
+		}
+	}
+	return true
+}
+
+// requiredBreaks reports whether the field generated for avp changes when its
+// rule switches between required and not required: wrappers become scalars,
+// and enums and, with Optional, scalars lose explicit presence. Grouped AVPs
+// are messages either way.
+func (d *differ) requiredBreaks(avp *dict.AVP) bool {
+	switch avp.Data.Type {
+	case datatype.GroupedType:
+		return false
+	case datatype.EnumeratedType:
+		return true
+	}
+	required, ok1 := d.types.For(avp.Data.Type, true)
+	optional, ok2 := d.types.For(avp.Data.Type, false)
+	if !ok1 || !ok2 || required != optional {
+		return true
+	}
+	_, scalar := goScalarTypes[required]
+	return d.opts.Optional && scalar
+}
+
+// diffEnum compares enum values by code. Dictionaries may give several
+// names, i.e. aliases, to the same code.
+func (d *differ) diffEnum(app *appIndex, owner string, oldEnum, newEnum []*dict.Enum) {
+	oldNames, newNames := enumNames(oldEnum), enumNames(newEnum)
+	for _, e := range oldEnum {
+		names, ok := newNames[e.Code]
+		switch {
+		case !ok:
+			d.report(app, true, "%s value %s (%d) removed", owner, e.Name, e.Code)
+		case !containsString(names, e.Name):
+			d.report(app, true, "%s value %d renamed from %s to %s", owner, e.Code, e.Name, strings.Join(names, ", "))
+		}
+	}
+	for _, e := range newEnum {
+		names, ok := oldNames[e.Code]
+		switch {
+		case !ok:
+			d.report(app, false, "%s value %s (%d) added", owner, e.Name, e.Code)
+			oldNames[e.Code] = []string{e.Name}
+		case !containsString(names, e.Name) && len(names) < len(newNames[e.Code]):
+			d.report(app, false, "%s value %d alias %s added", owner, e.Code, e.Name)
+		}
+	}
+}
+
+func enumNames(enums []*dict.Enum) map[int32][]string {
+	names := make(map[int32][]string)
+	for _, e := range enums {
+		names[e.Code] = append(names[e.Code], e.Name)
+	}
+	return names
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// diffRules compares the rules of a command or grouped AVP. Changing whether
+// an AVP repeats always breaks the generated field, while changing whether it
+// is required only does when the field changes, see requiredBreaks. Fields
+// are numbered in rule order with NumberSeq and no lock file, so that adding,
+// removing or moving a rule before others renumbers them.
+func (d *differ) diffRules(newApp *appIndex, owner string, oldRules, newRules []*dict.Rule) {
+	byName := make(map[string]*dict.Rule)
+	for _, r := range newRules {
+		byName[r.AVP] = r
+	}
+	seen := make(map[string]int)
+	for i, o := range oldRules {
+		seen[o.AVP] = i + 1
+		n, ok := byName[o.AVP]
+		if !ok {
+			d.report(newApp, true, "%s no longer contains %s", owner, o.AVP)
+			continue
+		}
+		if (o.Max != 1) != (n.Max != 1) {
+			d.report(newApp, true, "%s %s changed from max %d to max %d, repeated changes", owner, o.AVP, o.Max, n.Max)
+		} else if o.Max != n.Max || o.Min != n.Min {
+			d.report(newApp, false, "%s %s changed from min %d max %d to min %d max %d", owner, o.AVP, o.Min, o.Max, n.Min, n.Max)
+		}
+		if o.Required != n.Required {
+			breaking := true
+			if avp, _, err := d.b.lookup(newApp.id, newApp.vendorId, n.AVP); err == nil {
+				breaking = d.requiredBreaks(avp)
+			}
+			d.report(newApp, breaking, "%s %s changed from required=%t to required=%t", owner, o.AVP, o.Required, n.Required)
+		}
+	}
+	for _, n := range newRules {
+		if seen[n.AVP] == 0 {
+			d.report(newApp, false, "%s now contains %s", owner, n.AVP)
+		}
+	}
+	if d.opts.NumberFormat == NumberAvpCode || d.opts.Lock != nil {
+		return
+	}
+	var renumbered []string
+	for i, n := range newRules {
+		if old := seen[n.AVP]; old != 0 && old != i+1 {
+			renumbered = append(renumbered, fmt.Sprintf("%s from %d to %d", n.AVP, old, i+1))
+		}
+	}
+	if len(renumbered) > 0 {
+		d.report(newApp, true, "%s fields renumbered: %s", owner, strings.Join(renumbered, ", "))
+	}
+}
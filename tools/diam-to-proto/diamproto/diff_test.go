@@ -0,0 +1,107 @@
+package diamproto
+
+import (
+	"testing"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+)
+
+func TestDiffDictionaries(t *testing.T) {
+	a, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadDictionary("../testdata/diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// with AVP code numbering, field numbers do not depend on rule order
+	opts := Options{NumberFormat: NumberAvpCode}
+	want := []DiffChange{
+		{"Base (0)", "AVP Subscription-Id (443) Subscription-Id-Data changed from required=true to required=false", false},
+		{"Charging Control (4)", "application removed", true},
+		{"Gx Charging Control (16777238)", "command Credit-Control request no longer contains Event-Timestamp", true},
+		{"Gx Charging Control (16777238)", "command Credit-Control answer Charging-Rule-Name changed from max 0 to max 1, repeated changes", true},
+		{"Gx Charging Control (16777238)", "command Credit-Control answer now contains Offline", false},
+		{"Gx Charging Control (16777238)", "AVP Framed-IP-Address (8) type changed from OctetString to Address", false},
+		{"Gx Charging Control (16777238)", "AVP QoS-Information (1016) Max-Requested-Bandwidth-UL changed from required=false to required=true", true},
+		{"Gx Charging Control (16777238)", "AVP QoS-Class-Identifier (1028) value QCI_9 (9) removed", true},
+		{"Gx Charging Control (16777238)", "AVP QoS-Class-Identifier (1028) value QCI_5 (5) added", false},
+		{"Gx Charging Control (16777238)", "AVP Charging-Rule-Name (1005) type changed from OctetString to UTF8String", true},
+		{"Gx Charging Control (16777238)", "AVP Offline (1008 vendor 10415) added", false},
+	}
+	got := Diff(a, b, nil, opts)
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d:\ngot  %s\nwant %s", i, got[i], want[i])
+		}
+	}
+
+	if got := Diff(a, a, nil, Options{}); len(got) != 0 {
+		t.Errorf("got %d changes between identical dictionaries: %v", len(got), got)
+	}
+}
+
+// TestDiffSettings classifies the changes of testdata/diff for other
+// generation settings than in TestDiffDictionaries.
+func TestDiffSettings(t *testing.T) {
+	a, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadDictionary("../testdata/diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		renumbered   = "command Credit-Control answer fields renumbered: QoS-Information from 7 to 8"
+		stringChange = "AVP Subscription-Id (443) Subscription-Id-Data changed from required=true to required=false"
+		utf8Change   = "AVP Charging-Rule-Name (1005) type changed from OctetString to UTF8String"
+		ipChange     = "AVP Framed-IP-Address (8) type changed from OctetString to Address"
+	)
+	for _, tc := range []struct {
+		name string
+		opts Options
+		// whether the changes are reported, and breaking
+		renumbered, stringBreaking bool
+		// whether the type changes of Charging-Rule-Name and
+		// Framed-IP-Address are breaking
+		utf8Breaking, ipBreaking bool
+	}{
+		{"seq", Options{}, true, false, true, false},
+		{"lock", Options{NumberFormat: NumberSeq, Lock: &LockFile{}}, false, false, true, false},
+		{"optional", Options{NumberFormat: NumberAvpCode, Optional: true}, false, true, true, false},
+		{"typeMap", Options{NumberFormat: NumberAvpCode, TypeMap: TypeMap{
+			datatype.UTF8StringType: {"string", "google.protobuf.StringValue"},
+		}}, false, true, true, false},
+		{"octetStrings", Options{NumberFormat: NumberAvpCode, TypeMap: TypeMap{
+			datatype.OctetStringType: {"string", "string"},
+		}}, false, false, false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := make(map[string]bool)
+			for _, c := range Diff(a, b, nil, tc.opts) {
+				changes[c.Message] = c.Breaking
+			}
+			if breaking, ok := changes[renumbered]; ok != tc.renumbered || ok && !breaking {
+				t.Errorf("got renumbering reported %t (breaking %t), want %t", ok, breaking, tc.renumbered)
+			}
+			if breaking, ok := changes[stringChange]; !ok || breaking != tc.stringBreaking {
+				t.Errorf("got required change of Subscription-Id-Data breaking %t (reported %t), want %t", breaking, ok, tc.stringBreaking)
+			}
+			if breaking, ok := changes[utf8Change]; !ok || breaking != tc.utf8Breaking {
+				t.Errorf("got type change of Charging-Rule-Name breaking %t (reported %t), want %t", breaking, ok, tc.utf8Breaking)
+			}
+			if breaking, ok := changes[ipChange]; !ok || breaking != tc.ipBreaking {
+				t.Errorf("got type change of Framed-IP-Address breaking %t (reported %t), want %t", breaking, ok, tc.ipBreaking)
+			}
+			if !changes["command Credit-Control request no longer contains Event-Timestamp"] {
+				t.Error("rule removal not reported as breaking")
+			}
+		})
+	}
+}
@@ -0,0 +1,799 @@
+package diamproto
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+)
+
+// Field number formats.
+const (
+	NumberSeq     = "seq"
+	NumberAvpCode = "avpcode"
+)
+
+// Options configures a Generator. Empty values take the defaults of the
+// command line flags of the same name.
+type Options struct {
+	// Interfaces are the aliases, application names or IDs of the
+	// applications to generate, one proto file each.
+	Interfaces []string
+	// Commands restricts the commands generated for an interface, keyed as
+	// in Interfaces, to the listed command names or codes.
+	Commands map[string][]string
+	// ExcludeCommands lists, keyed as in Interfaces, command names or codes
+	// not to generate, even if listed by Commands. Only the grouped AVPs and
+	// enums reachable from the generated commands are generated.
+	ExcludeCommands map[string][]string
+	// NumberFormat is NumberSeq or NumberAvpCode.
+	NumberFormat string
+	// Package is the proto package, diameterpb by default.
+	Package string
+	// GoPackage is the go_package option, derived from Package if empty.
+	GoPackage string
+	// OptionsGoPackage is the go_package option of diameter/options.proto,
+	// a diameter package next to GoPackage if empty.
+	OptionsGoPackage string
+	// Conflict is ConflictMerge, ConflictNamespace or ConflictFail.
+	Conflict string
+	// Optional emits proto3 optional scalars instead of wrapper types for
+	// AVPs that are neither required nor repeated.
+	Optional bool
+	// TypeMap overrides the proto types of Diameter data types.
+	TypeMap TypeMap
+	// Renames changes the generated names of messages, enums and fields.
+	Renames Renames
+	// Overrides changes the types and names generated for AVPs, keyed as
+	// described by Override.
+	Overrides map[string]Override
+	// Oneofs groups fields of a message, keyed by message name then oneof
+	// name, into a oneof as the AVPs are alternatives. Names are those
+	// generated before renames.
+	Oneofs map[string]map[string][]string
+	// Services emits a gRPC service per application, with a method per
+	// command.
+	Services bool
+	// UnknownAvps adds to every message a repeated diameter.RawAvp field
+	// keeping the AVPs it has no field for, e.g. those of the *[ AVP ]
+	// extension point, so that converting a message is lossless. They are
+	// encoded after the AVPs of the other fields.
+	UnknownAvps bool
+	// Envelopes emits, for every command, a message pairing a
+	// diameter.DiameterHeader with the request or answer, so that whole
+	// Diameter messages can be transported.
+	Envelopes bool
+	// Lock, if not nil, keeps field numbers stable and is updated with the
+	// numbers assigned by the generation.
+	Lock *LockFile
+}
+
+// Generator builds proto files from the applications of a Dictionary. Each
+// call to Generate starts from a clean state, so a Generator can be reused.
+type Generator struct {
+	dict  *Dictionary
+	opts  Options
+	types TypeMap
+	// variants of each grouped and enumerated type built by the applications,
+	// and the types retained once conflicts are resolved
+	variants map[string]*typeVariants
+	parsed   map[string]CompositeField
+	// grouped types being expanded and already expanded, per application
+	building map[groupKey]bool
+	built    map[groupKey]bool
+	// keys of the overrides applied
+	overridden map[string]bool
+}
+
+type groupKey struct {
+	appId uint32
+	name  string
+}
+
+// Node is the list of rules a message is built from.
+type Node struct {
+	appId    uint32
+	rules    []*dict.Rule
+	vendorId uint32
+}
+
+// NewGenerator returns a Generator of the applications of d.
+func NewGenerator(d *Dictionary, opts Options) *Generator {
+	if opts.NumberFormat == "" {
+		opts.NumberFormat = NumberSeq
+	}
+	if opts.Package == "" {
+		opts.Package = "diameterpb"
+	}
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictMerge
+	}
+	return &Generator{dict: d, opts: opts, types: withDefaults(opts.TypeMap)}
+}
+
+// Result is the outcome of Generate: the model of the generated files and
+// their rendered content.
+type Result struct {
+	// Files holds a proto file per application, in the order of
+	// Options.Interfaces, followed by common.proto.
+	Files []*ProtoFile
+	// Options is diameter/options.proto, declaring the custom options Files
+	// are annotated with.
+	Options *ProtoFile
+	// Protos are the rendered Files and Options.
+	Protos []File
+	// Converters are the Go converters between go-diameter messages and the
+	// messages of Files.
+	Converters []File
+}
+
+// File is a rendered file, named relative to the output directory.
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Write creates the file inside dir, creating directories if necessary.
+func (f File) Write(dir string) error {
+	path := filepath.Join(dir, f.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, f.Content, 0644)
+}
+
+// Generate builds the messages of the commands of every application, resolves
+// the types conflicting between applications and renders the files.
+func (g *Generator) Generate() (*Result, error) {
+	g.variants = make(map[string]*typeVariants)
+	g.parsed = make(map[string]CompositeField)
+	g.building = make(map[groupKey]bool)
+	g.built = make(map[groupKey]bool)
+	g.overridden = make(map[string]bool)
+
+	var enabledApps = make(map[uint32]string)
+	var appPrefixes = make(map[uint32]string)
+	var appIds []uint32
+	for _, token := range g.opts.Interfaces {
+		id, key, err := ResolveApplication(g.dict.P, token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interface: %w", err)
+		}
+		if other, ok := enabledApps[id]; ok {
+			return nil, fmt.Errorf("invalid interface: %s and %s both refer to application %d", other, key, id)
+		}
+		enabledApps[id] = key
+		appPrefixes[id] = strings.ToUpper(key[:1]) + key[1:]
+		appIds = append(appIds, id)
+	}
+	selection, err := g.commandSelection(enabledApps)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.checkOverrides(); err != nil {
+		return nil, err
+	}
+
+	var priority int = 0
+	var appFields = make(map[uint32][]CompositeField)
+
+	for _, app := range g.dict.P.Apps() {
+		if _, ok := enabledApps[app.ID]; ok {
+			for _, command := range app.Command {
+				if !selection.selected(app.ID, command) {
+					continue
+				}
+				request := fmt.Sprintf("%s%s", app.Name, command.Name)
+				replacer := strings.NewReplacer("TGPP", "", " ", "", "-", "")
+				request = replacer.Replace(request)
+
+				var vendorId = uint32(dict.UndefinedVendorID)
+				if len(app.Vendor) > 0 {
+					vendorId = app.Vendor[0].ID
+				}
+				reqField := g.build(request+"RequestPB", priority,
+					&Node{appId: app.ID, rules: command.Request.Rule, vendorId: vendorId},
+				)
+				reqField.AppId, reqField.CommandCode, reqField.Command, reqField.Request = app.ID, command.Code, command.Name, true
+				appFields[app.ID] = append(appFields[app.ID], reqField)
+				priority++
+				ansField := g.build(request+"AnswerPB", priority,
+					&Node{appId: app.ID, rules: command.Answer.Rule, vendorId: vendorId},
+				)
+				ansField.AppId, ansField.CommandCode, ansField.Command = app.ID, command.Code, command.Name
+				appFields[app.ID] = append(appFields[app.ID], ansField)
+				priority++
+			}
+		}
+	}
+	if err := selection.check(); err != nil {
+		return nil, err
+	}
+	var unused []string
+	for key := range g.opts.Overrides {
+		if !g.overridden[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	for _, key := range unused {
+		log.Printf("*** Override of %s ignored, no generated field uses this AVP", key)
+	}
+
+	if err := g.resolveConflicts(enabledApps, appPrefixes, appFields); err != nil {
+		return nil, fmt.Errorf("failed to resolve type conflicts: %w", err)
+	}
+
+	var files []*ProtoFile
+	for _, id := range appIds {
+		files = append(files, &ProtoFile{Name: enabledApps[id] + ".proto", Messages: appFields[id]})
+	}
+	common := &ProtoFile{Name: "common.proto"}
+	var names []string
+	for name := range g.parsed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		common.Messages = append(common.Messages, g.parsed[name])
+	}
+	files = append(files, common)
+	if err := g.groupOneofs(files); err != nil {
+		return nil, err
+	}
+	g.rename(files)
+
+	for _, file := range files {
+		file.Pkg = g.opts.Package
+		file.GoPackage = g.opts.GoPackage
+		sortMessages(file.Messages)
+		for i, v := range file.Messages {
+			numberFields(v, g.opts.NumberFormat)
+			if v.ProtoDataType != "message" {
+				continue
+			}
+			if g.opts.Lock != nil {
+				g.opts.Lock.apply(&file.Messages[i], g.opts.NumberFormat)
+			}
+			file.Messages[i].UnknownAvps = g.opts.UnknownAvps
+		}
+	}
+	for i, id := range appIds {
+		if g.opts.Envelopes {
+			files[i].Envelopes = newEnvelopes(appPrefixes[id], files[i].Messages)
+		}
+		if g.opts.Services {
+			if service := newService(appPrefixes[id], files[i].Messages); len(service.Methods) > 0 {
+				files[i].Services = []Service{service}
+			}
+		}
+	}
+	linkImports(files)
+
+	options := newOptionsFile(optionsGoPackage(g.opts))
+	result := &Result{Files: files, Options: options}
+	for _, file := range append(files, result.Options) {
+		result.Protos = append(result.Protos, File{Name: file.Name, Content: []byte(file.String())})
+	}
+	for _, file := range files {
+		converter := newConverterFile(file, options)
+		converter.envelopes = g.opts.Envelopes
+		src, err := converter.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to render Go converters: %s: %w", file.Name, err)
+		}
+		result.Converters = append(result.Converters, File{Name: converter.Name(), Content: src})
+	}
+	return result, nil
+}
+
+// commandSelection lists, per application, the command names or codes to
+// generate and to skip. Applications without an include list generate all of
+// their commands but the excluded ones.
+type commandSelection struct {
+	include map[uint32][]string
+	exclude map[uint32][]string
+	names   map[uint32]string
+	matched map[uint32]map[string]bool
+}
+
+func (g *Generator) commandSelection(enabledApps map[uint32]string) (*commandSelection, error) {
+	s := &commandSelection{
+		include: make(map[uint32][]string),
+		exclude: make(map[uint32][]string),
+		names:   make(map[uint32]string),
+		matched: make(map[uint32]map[string]bool),
+	}
+	for _, list := range []struct {
+		commands map[string][]string
+		tokens   map[uint32][]string
+	}{{g.opts.Commands, s.include}, {g.opts.ExcludeCommands, s.exclude}} {
+		for key, tokens := range list.commands {
+			id, _, err := ResolveApplication(g.dict.P, key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid command selection: %w", err)
+			}
+			if _, ok := enabledApps[id]; !ok {
+				return nil, fmt.Errorf("invalid command selection: application %s is not generated", key)
+			}
+			list.tokens[id] = append(list.tokens[id], tokens...)
+			s.names[id] = key
+			if s.matched[id] == nil {
+				s.matched[id] = make(map[string]bool)
+			}
+		}
+	}
+	return s, nil
+}
+
+// selected reports whether the command of the application is generated:
+// listed by name or code, or the application has no include list, and not
+// excluded.
+func (s *commandSelection) selected(appId uint32, command *dict.Command) bool {
+	_, listed := s.include[appId]
+	included := s.match(appId, s.include[appId], command) || !listed
+	excluded := s.match(appId, s.exclude[appId], command)
+	return included && !excluded
+}
+
+// match reports whether one of the tokens is the name or code of the command,
+// recording the matching tokens.
+func (s *commandSelection) match(appId uint32, tokens []string, command *dict.Command) bool {
+	found := false
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+		if strings.EqualFold(token, command.Name) || token == strconv.FormatUint(uint64(command.Code), 10) {
+			s.matched[appId][token] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// check reports the listed commands no application defines, as these are
+// most likely typos.
+func (s *commandSelection) check() error {
+	var unknown []string
+	for _, lists := range []map[uint32][]string{s.include, s.exclude} {
+		for id, tokens := range lists {
+			for _, token := range tokens {
+				if !s.matched[id][strings.TrimSpace(token)] {
+					unknown = append(unknown, fmt.Sprintf("%s of %s", token, s.names[id]))
+				}
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("invalid command selection: unknown commands %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// groupOneofs makes the fields listed by the oneofs of the options members
+// of a oneof. Members give presence to the oneof, so they are neither
+// required nor optional, and cannot be repeated. A oneof with a required
+// member is required instead.
+func (g *Generator) groupOneofs(files []*ProtoFile) error {
+	found := make(map[string]bool)
+	for _, file := range files {
+		for _, m := range file.Messages {
+			oneofs, ok := g.opts.Oneofs[m.Name]
+			if !ok || m.ProtoDataType != "message" {
+				continue
+			}
+			found[m.Name] = true
+			fields := make(map[string]*GeneralField)
+			for _, f := range m.Fields {
+				if field, ok := f.(*GeneralField); ok {
+					fields[field.VarName] = field
+				}
+			}
+			for oneof, names := range oneofs {
+				if _, ok := fields[oneof]; ok {
+					return fmt.Errorf("oneof %s of %s has the name of a field", oneof, m.Name)
+				}
+				for _, name := range names {
+					field, ok := fields[name]
+					switch {
+					case !ok:
+						return fmt.Errorf("oneof %s of %s: unknown field %s", oneof, m.Name, name)
+					case field.Repeated:
+						return fmt.Errorf("oneof %s of %s: field %s is repeated", oneof, m.Name, name)
+					case field.Oneof != "":
+						return fmt.Errorf("oneof %s of %s: field %s already belongs to oneof %s", oneof, m.Name, name, field.Oneof)
+					}
+					field.Oneof, field.OneofRequired = oneof, field.Required
+					field.Required, field.Optional = false, false
+				}
+			}
+		}
+	}
+	var unused []string
+	for name := range g.opts.Oneofs {
+		if !found[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		log.Printf("*** Oneofs of %s ignored, no such message", name)
+	}
+	return nil
+}
+
+// rename applies the renames of the options to the messages, enums and
+// fields of files, and to the fields referring to renamed types. Field
+// renames are keyed by the message name before renaming.
+func (g *Generator) rename(files []*ProtoFile) {
+	renames := g.opts.Renames
+	used := make(map[string]bool)
+	for _, file := range files {
+		for i, m := range file.Messages {
+			for _, f := range m.Fields {
+				field, ok := f.(*GeneralField)
+				if !ok {
+					continue
+				}
+				key := m.Name + "." + field.VarName
+				if name, ok := renames.Fields[key]; ok {
+					field.VarName = name
+					used[key] = true
+				}
+				if name, ok := renames.Messages[field.DataType]; ok {
+					field.DataType = name
+				}
+			}
+			if name, ok := renames.Messages[m.Name]; ok {
+				used[m.Name] = true
+				file.Messages[i].Name = name
+			}
+		}
+	}
+	for _, names := range []map[string]string{renames.Messages, renames.Fields} {
+		for from := range names {
+			if !used[from] {
+				log.Printf("*** Rename of %s ignored, no such message or field", from)
+			}
+		}
+	}
+}
+
+// commands returns the request and answer messages of every command found in
+// messages, in message order.
+func commands(messages []CompositeField) [][2]CompositeField {
+	var pairs [][2]CompositeField
+	for _, request := range messages {
+		if !request.Request {
+			continue
+		}
+		for _, answer := range messages {
+			if !answer.Request && answer.CommandCode == request.CommandCode && answer.AppId == request.AppId {
+				pairs = append(pairs, [2]CompositeField{request, answer})
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// commandName returns the name of a command in camel case, e.g. CreditControl.
+func commandName(command string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(kebabToCamelCase(command))
+}
+
+// newService returns the service named name with a method per command whose
+// request and answer are found in messages.
+func newService(name string, messages []CompositeField) Service {
+	service := Service{Name: name}
+	for _, pair := range commands(messages) {
+		service.Methods = append(service.Methods, Method{
+			Name:     commandName(pair[0].Command),
+			Request:  pair[0].Name,
+			Response: pair[1].Name,
+		})
+	}
+	return service
+}
+
+// newEnvelopes returns the envelopes of the commands whose request and answer
+// are found in messages, named after the application prefix and the command,
+// e.g. GxCreditControlEnvelope.
+func newEnvelopes(prefix string, messages []CompositeField) []Envelope {
+	var envelopes []Envelope
+	for _, pair := range commands(messages) {
+		envelopes = append(envelopes, Envelope{
+			Name:    prefix + commandName(pair[0].Command) + "Envelope",
+			Request: pair[0].Name,
+			Answer:  pair[1].Name,
+		})
+	}
+	return envelopes
+}
+
+// Override changes what is generated for an AVP. Empty settings keep the
+// generated values.
+type Override struct {
+	// Type is the proto type of the field, e.g. string for an OctetString
+	// AVP known to hold text. It replaces the enum of Enumerated AVPs and
+	// cannot be set for Grouped AVPs.
+	Type string `yaml:"type" json:"type"`
+	// Import is the proto file declaring Type, if it is not a scalar or
+	// well-known type.
+	Import string `yaml:"import" json:"import"`
+	// Field and JsonName are the name and json_name of the field.
+	Field    string `yaml:"field" json:"field"`
+	JsonName string `yaml:"jsonName" json:"jsonName"`
+	// Message is the name of the message or enum generated for a Grouped or
+	// Enumerated AVP.
+	Message string `yaml:"message" json:"message"`
+}
+
+// override returns the override of the AVP and records it as used.
+func (g *Generator) override(avp *dict.AVP) Override {
+	key, override, ok := g.lookupOverride(avp)
+	if ok {
+		g.overridden[key] = true
+	}
+	return override
+}
+
+// lookupOverride returns the override of the AVP and its key, keyed by AVP
+// name, by code and vendor as in 1032:10415, or by code alone for any vendor.
+func (g *Generator) lookupOverride(avp *dict.AVP) (string, Override, bool) {
+	for _, key := range []string{
+		avp.Name,
+		fmt.Sprintf("%d:%d", avp.Code, avp.VendorID),
+		strconv.FormatUint(uint64(avp.Code), 10),
+	} {
+		if override, ok := g.opts.Overrides[key]; ok {
+			return key, override, true
+		}
+	}
+	return "", Override{}, false
+}
+
+// checkOverrides returns an error if the type of an override cannot hold the
+// values of an AVP it applies to, as its converter would not compile.
+func (g *Generator) checkOverrides() error {
+	if len(g.opts.Overrides) == 0 {
+		return nil
+	}
+	for _, app := range g.dict.P.Apps() {
+		for _, avp := range app.AVP {
+			key, override, ok := g.lookupOverride(avp)
+			if !ok || override.Type == "" || avp.Data.Type == datatype.GroupedType {
+				continue
+			}
+			if !holds(override.Type, avp.Data.Type) {
+				return fmt.Errorf("invalid override %s of AVP %s: proto type %s cannot hold %s values",
+					key, avp.Name, override.Type, avp.Data.TypeName)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Generator) build(name string, priority int, node *Node) CompositeField {
+	composite := CompositeField{Name: name, Priority: priority, ProtoDataType: "message"}
+	for _, r := range node.rules {
+		avp, err := g.dict.search(node.appId, node.vendorId, r.AVP)
+		if err != nil {
+			continue
+		}
+		typeName := kebabToCamelCase(avp.Name)
+		if upperSnakeCase(avp.Name) == "" {
+			// no letter or digit to name the field after
+			typeName = fmt.Sprintf("Avp%d", avp.Code)
+		}
+		a := []rune(typeName)
+		a[0] = unicode.ToLower(a[0])
+		varName := string(a)
+		override := g.override(avp)
+		if override.Field != "" {
+			varName = override.Field
+		}
+		if override.Message != "" {
+			typeName = override.Message
+		}
+		field := &GeneralField{
+			VarName:       varName,
+			AvpCode:       avp.Code,
+			VendorId:      avp.VendorID,
+			AvpType:       avp.Data.Type,
+			Mandatory:     strings.Contains(avp.Must, "M"),
+			Protected:     strings.Contains(avp.Must, "P"),
+			JsonFieldName: avp.Name,
+			Source:        g.dict.sources[avp],
+			Repeated:      r.Max != 1,
+			Required:      r.Required,
+			Min:           r.Min,
+			Max:           r.Max,
+		}
+		if override.JsonName != "" {
+			field.JsonFieldName = override.JsonName
+		}
+		if override.Type != "" && avp.Data.Type == datatype.GroupedType {
+			log.Printf("*** Type override of grouped AVP %s ignored", avp.Name)
+			override.Type = ""
+		}
+		avpType := avp.Data.Type
+		if avpType == datatype.EnumeratedType && override.Type == "" && len(avp.Data.Enum) == 0 {
+			log.Printf("*** Enumerated AVP %s has no values, using the Integer32 type", avp.Name)
+			avpType = datatype.Integer32Type
+		}
+		switch {
+		case avpType == datatype.EnumeratedType && override.Type == "":
+			field.DataType = typeName + "Enum"
+			if override.Message != "" {
+				field.DataType = override.Message
+			}
+			enumField := processEnumField(field.DataType, avp)
+			// singular enums keep explicit presence, as zero may be a valid code
+			field.Optional = !field.Required && !field.Repeated
+			enumField.Source = g.dict.sources[avp]
+			g.record(node.appId, field.DataType, enumField)
+		case avp.Data.Type == datatype.GroupedType:
+			field.DataType = typeName
+			key := groupKey{appId: node.appId, name: typeName}
+			if g.building[key] {
+				log.Printf("Type %s refers to itself", typeName)
+			} else if !g.built[key] {
+				g.building[key] = true
+				groupField := g.build(typeName, 50, &Node{appId: node.appId, rules: avp.Data.Rule, vendorId: node.vendorId})
+				groupField.Source = g.dict.sources[avp]
+				delete(g.building, key)
+				g.built[key] = true
+				g.record(node.appId, field.DataType, groupField)
+			}
+		default:
+			// enumerated AVPs mapped to scalars keep explicit presence, as
+			// for their enums
+			optional := (g.opts.Optional || avp.Data.Type == datatype.EnumeratedType) && !field.Required && !field.Repeated
+			dataType, ok := g.types.For(avpType, field.Required || optional)
+			if override.Type != "" {
+				dataType, ok = override.Type, true
+				field.Import = override.Import
+			}
+			if !ok {
+				log.Printf("%s data type of AVP %s not mapped, using bytes", avp.Data.TypeName, avp.Name)
+				dataType = "bytes"
+			}
+			field.DataType = dataType
+			_, scalar := goScalarTypes[dataType]
+			field.Optional = optional && scalar
+		}
+		composite.Fields = append(composite.Fields, field)
+	}
+	return composite
+}
+
+// sortMessages orders messages by priority, then by descending field count
+// and finally by name.
+func sortMessages(fields []CompositeField) {
+	sort.SliceStable(fields, func(i, j int) bool {
+		diff := fields[i].Priority - fields[j].Priority
+		if diff == 0 {
+			diff = len(fields[j].Fields) - len(fields[i].Fields)
+			if diff == 0 {
+				return fields[i].Name < fields[j].Name
+			}
+		}
+		return diff < 0
+	})
+}
+
+// numberFields assigns proto field numbers either sequentially in rule order
+// or from the AVP codes, in which case fields are sorted by code first.
+func numberFields(v CompositeField, format string) {
+	// ascending sort fields based on avp codes
+	if format == NumberAvpCode {
+		sort.SliceStable(v.Fields, func(i, j int) bool {
+			return v.Fields[i].GetCode() < v.Fields[j].GetCode()
+		})
+	}
+	for i, f := range v.Fields {
+		if format == NumberAvpCode {
+			f.SetIndex(int(f.GetCode()))
+		} else {
+			f.SetIndex(i + 1)
+		}
+	}
+}
+
+// processEnumField builds a top-level enum whose values are prefixed by the
+// upper snake case AVP name, e.g. QOS_CLASS_IDENTIFIER_QCI_1, as enum values
+// share the package scope. proto3 requires a zero first value, so a
+// PREFIX_UNSPECIFIED value is added unless the dictionary defines code 0.
+// Values whose names collide once sanitized are suffixed with their code, and
+// AVP names without letters or digits are replaced by AVP_<code>.
+func processEnumField(name string, avp *dict.AVP) CompositeField {
+	composite := CompositeField{Name: name, Priority: 10, ProtoDataType: "enum"}
+	prefix := upperSnakeCase(avp.Name)
+	if prefix == "" {
+		prefix = fmt.Sprintf("AVP_%d", avp.Code)
+	} else if prefix[0] >= '0' && prefix[0] <= '9' {
+		// same special case as kebabToCamelCase, mainly for 3GPP
+		if prefix[0] == '3' {
+			prefix = "T" + prefix[1:]
+		} else {
+			prefix = "X" + prefix
+		}
+	}
+	var zero []Field
+	used := make(map[string]bool)
+	for _, enum := range avp.Data.Enum {
+		value := upperSnakeCase(enum.Name)
+		if value == "" {
+			value = "VALUE"
+		}
+		valueName := prefix + "_" + value
+		if used[valueName] {
+			valueName = fmt.Sprintf("%s_%d", valueName, enum.Code)
+		}
+		used[valueName] = true
+		field := &EnumField{Name: valueName, Code: enum.Code}
+		if enum.Code == 0 && zero == nil {
+			zero = []Field{field}
+			continue
+		}
+		composite.Fields = append(composite.Fields, field)
+	}
+	if zero == nil {
+		zero = []Field{&EnumField{Name: prefix + "_UNSPECIFIED", Code: 0}}
+	}
+	composite.Fields = append(zero, composite.Fields...)
+	return composite
+}
+
+// upperSnakeCase converts a dictionary name such as QoS-Class-Identifier or
+// "IPv4 Address" into an upper snake case proto identifier.
+func upperSnakeCase(name string) string {
+	var b strings.Builder
+	separator := false
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if separator && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			separator = false
+			b.WriteRune(unicode.ToUpper(r))
+		} else {
+			separator = true
+		}
+	}
+	return b.String()
+}
+
+func kebabToCamelCase(kebab string) (camelCase string) {
+	isToUpper := false
+	isFirstLetter := true
+	for _, runeValue := range kebab {
+		if isFirstLetter {
+			isFirstLetter = false
+			// special case where protoc fails to compile enums whose names starting with numeric char, mainly '3' as in 3GPP
+			if string(runeValue) == "3" {
+				camelCase += "T"
+				continue
+			}
+		}
+		if isToUpper {
+			camelCase += strings.ToUpper(string(runeValue))
+			isToUpper = false
+		} else {
+			if runeValue == '-' {
+				isToUpper = true
+			} else {
+				camelCase += string(runeValue)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,521 @@
+package diamproto
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+)
+
+func TestGenerate(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGenerator(d, Options{Interfaces: []string{"gx"}, GoPackage: "example.com/diameterpb"})
+	result, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, file := range result.Files {
+		names = append(names, file.Name)
+	}
+	if len(names) != 2 || names[0] != "gx.proto" || names[1] != "common.proto" {
+		t.Fatalf("got files %v, want [gx.proto common.proto]", names)
+	}
+	if len(result.Protos) != 3 || result.Protos[2].Name != optionsImport {
+		t.Errorf("got %d rendered proto files, want 3 ending with %s", len(result.Protos), optionsImport)
+	}
+	if len(result.Converters) != 2 || result.Converters[0].Name != "gx_diam.go" {
+		t.Errorf("got %d converters, want gx_diam.go and common_diam.go", len(result.Converters))
+	}
+
+	request := result.Files[0].Messages[0]
+	if request.Name != "GxChargingControlCreditControlRequestPB" || !request.Request || request.CommandCode != 272 {
+		t.Errorf("unexpected first message %s request=%t code=%d", request.Name, request.Request, request.CommandCode)
+	}
+	first, ok := request.Fields[0].(*GeneralField)
+	if !ok || first.VarName != "sessionId" || first.Index != 1 || !first.Required {
+		t.Errorf("unexpected first field %+v", request.Fields[0])
+	}
+
+	// the generator state is reset by every run
+	again, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range result.Protos {
+		if !bytes.Equal(result.Protos[i].Content, again.Protos[i].Content) {
+			t.Errorf("%s differs between runs", result.Protos[i].Name)
+		}
+	}
+}
+
+func TestGenerateTypeMap(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := TypeMap{}
+	if err := types.Set("UTF8String=bytes"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewGenerator(d, Options{Interfaces: []string{"gx"}, TypeMap: types}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := result.Files[0].Messages[0].Fields[0].(*GeneralField)
+	if field.DataType != "bytes" {
+		t.Errorf("got Session-Id type %s, want bytes", field.DataType)
+	}
+	if typ, _ := protoTypes.For(field.AvpType, true); typ != "string" {
+		t.Errorf("default type map was modified: UTF8String is %s", typ)
+	}
+}
+
+func TestTypeMapSet(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		ok    bool
+	}{
+		{"Unsigned32=uint64", true},
+		{"Unsigned32=uint32:google.protobuf.Int64Value", true},
+		{"Float64=float", true},
+		{"OctetString=string:google.protobuf.BytesValue", true},
+		{"Time=example.Time", true},
+		{"Unsigned32=string", false},
+		{"Unsigned32=bool", false},
+		{"Unsigned32=uint32:google.protobuf.StringValue", false},
+		{"UTF8String=int32", false},
+		{"Time=uint32", false},
+		{"Address=google.protobuf.Timestamp", false},
+		{"Enumerated=uint32", false},
+	} {
+		err := TypeMap{}.Set(tc.value)
+		if (err == nil) != tc.ok {
+			t.Errorf("Set(%q) returned %v, want success %t", tc.value, err, tc.ok)
+		}
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{"dictionaries": ["dict", "/abs"], "interfaces": ["gy"], "typeMap": {"Unsigned32": "uint32:uint32"}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Dictionaries[0] != filepath.Join(dir, "dict") || config.Dictionaries[1] != "/abs" {
+		t.Errorf("dictionaries not resolved relative to the file: %v", config.Dictionaries)
+	}
+	// settings missing from the file keep the defaults
+	if config.Package != "diameterpb" || config.NumberFormat != NumberSeq {
+		t.Errorf("got package %q number format %q, want the defaults", config.Package, config.NumberFormat)
+	}
+	opts, err := config.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ, _ := opts.TypeMap.For(datatype.Unsigned32Type, false); typ != "uint32" {
+		t.Errorf("got optional Unsigned32 type %s, want uint32", typ)
+	}
+}
+
+func TestReadConfigOverrideType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "interfaces: [gx]\noverrides:\n  QoS-Class-Identifier: {type: string}\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := config.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewGenerator(d, opts).Generate()
+	if err == nil || !strings.Contains(err.Error(), "AVP QoS-Class-Identifier") {
+		t.Errorf("got error %v, want an invalid override of QoS-Class-Identifier", err)
+	}
+}
+
+func TestGenerateCommands(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewGenerator(d, Options{Interfaces: []string{"gx"}, Commands: map[string][]string{"gx": {"Re-Auth"}}}).Generate()
+	if err == nil || !strings.Contains(err.Error(), "Re-Auth of gx") {
+		t.Errorf("got error %v, want an unknown command error", err)
+	}
+	result, err := NewGenerator(d, Options{Interfaces: []string{"gx"}, Commands: map[string][]string{"gx": {"272"}}}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(result.Files[0].Messages); n != 2 {
+		t.Errorf("got %d messages, want the Credit-Control request and answer", n)
+	}
+}
+
+func TestGenerateOverrides(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewGenerator(d, Options{Interfaces: []string{"gx"}, Overrides: map[string]Override{
+		"8":                    {Type: "example.Address", Import: "example/address.proto"},
+		"QoS-Class-Identifier": {Type: "uint32"},
+	}}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range result.Files[1].Messages {
+		if m.Name == "QoSClassIdentifierEnum" {
+			t.Error("enum generated for an AVP overridden to uint32")
+		}
+	}
+	gx := string(result.Protos[0].Content)
+	if !strings.Contains(gx, `import "example/address.proto";`) || !strings.Contains(gx, "example.Address framedIPAddress") {
+		t.Errorf("overridden Framed-IP-Address type or import missing:\n%s", gx)
+	}
+	common := string(result.Protos[1].Content)
+	if !strings.Contains(common, "optional uint32 qoSClassIdentifier") {
+		t.Errorf("QoS-Class-Identifier overridden to uint32 lost explicit presence:\n%s", common)
+	}
+}
+
+func TestGenerateOptionsFile(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// options.proto is the same whatever the settings, so that generated
+	// packages can share it
+	var options []string
+	for _, opts := range []Options{
+		{Interfaces: []string{"gx"}, GoPackage: "example.com/gxpb"},
+		{Interfaces: []string{"gy"}, GoPackage: "example.com/gypb;gy", UnknownAvps: true, Envelopes: true},
+	} {
+		result, err := NewGenerator(d, opts).Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		options = append(options, result.Options.String())
+	}
+	if options[0] != options[1] {
+		t.Errorf("options.proto differs between generations:\n%s\n%s", options[0], options[1])
+	}
+	if !strings.Contains(options[0], `option go_package = "example.com/diameter";`) {
+		t.Errorf("options.proto not in the diameter package next to the generated one:\n%s", options[0])
+	}
+}
+
+func TestGenerateOneofs(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oneofs := map[string]map[string][]string{
+		"SubscriptionId": {"id": {"subscriptionIdType", "subscriptionIdData"}},
+		"GxChargingControlCreditControlRequestPB": {"qos": {"qoSInformation", "eventTimestamp"}},
+	}
+	result, err := NewGenerator(d, Options{Interfaces: []string{"gx"}, Oneofs: oneofs}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	common := string(result.Protos[1].Content)
+	if !strings.Contains(common, "\toneof id {\n\t\tSubscriptionIdTypeEnum subscriptionIdType = 1") {
+		t.Errorf("oneof id missing:\n%s", common)
+	}
+	if strings.Contains(common, "(diameter.required) = true, (diameter.avp_type) = \"Enumerated\"") {
+		t.Error("oneof member still required")
+	}
+	// a oneof of required members must be set, a grouped member is validated
+	// when chosen
+	converters := string(result.Converters[0].Content) + string(result.Converters[1].Content)
+	for _, check := range []string{
+		"if m.Id == nil {\n\t\treturn fmt.Errorf(\"missing required AVP, one of Subscription-Id-Type, Subscription-Id-Data\")",
+		"if x, ok := m.Qos.(*GxChargingControlCreditControlRequestPB_QoSInformation); ok && x.QoSInformation != nil {\n" +
+			"\t\tif err := x.QoSInformation.Validate(); err != nil {",
+	} {
+		if !strings.Contains(converters, check) {
+			t.Errorf("Validate lacks %q:\n%s", check, converters)
+		}
+	}
+	if strings.Contains(converters, "m.Qos == nil") {
+		t.Error("oneof of optional members required")
+	}
+
+	oneofs = map[string]map[string][]string{"UsedServiceUnit": {"reason": {"reportingReason"}}}
+	if _, err := NewGenerator(d, Options{Interfaces: []string{"gy"}, Oneofs: oneofs}).Generate(); err == nil {
+		t.Error("expected an error for a repeated oneof member")
+	}
+}
+
+// extraDictionary writes a dictionary file holding data to a temporary
+// folder, loaded along with the sample dictionaries.
+func extraDictionary(t *testing.T, data string) *Dictionary {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "extra.xml"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadDictionary("../testdata/dict", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestGenerateCardinality(t *testing.T) {
+	d := extraDictionary(t, `<?xml version="1.0" encoding="UTF-8"?>
+<diameter>
+	<application id="9999" type="auth" name="Bounded">
+		<command code="9999" short="BD" name="Bounded">
+			<request>
+				<rule avp="Session-Id" required="true" max="1"/>
+				<rule avp="Charging-Rule-Name" required="true" min="2" max="4"/>
+				<rule avp="QoS-Information" required="true" max="1"/>
+			</request>
+			<answer>
+				<rule avp="Session-Id" required="true" max="1"/>
+			</answer>
+		</command>
+	</application>
+</diameter>`)
+	result, err := NewGenerator(d, Options{Interfaces: []string{"9999"}}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto := string(result.Protos[0].Content)
+	if !strings.Contains(proto, "(diameter.min) = 2, (diameter.max) = 4") {
+		t.Errorf("Charging-Rule-Name bounds missing:\n%s", proto)
+	}
+	converter := string(result.Converters[0].Content)
+	for _, check := range []string{
+		"if len(m.ChargingRuleName) < 2 {",
+		"if len(m.ChargingRuleName) > 4 {",
+		"if m.QoSInformation == nil {\n\t\treturn fmt.Errorf(\"missing required AVP QoS-Information\")",
+	} {
+		if !strings.Contains(converter, check) {
+			t.Errorf("Validate lacks %q:\n%s", check, converter)
+		}
+	}
+}
+
+func TestGenerateValidateRequired(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Validate of an empty Credit-Control request
+	validate := func(types TypeMap) string {
+		t.Helper()
+		result, err := NewGenerator(d, Options{Interfaces: []string{"gx"}, TypeMap: types}).Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		converter := string(result.Converters[0].Content)
+		start := strings.Index(converter, "func (m *GxChargingControlCreditControlRequestPB) Validate() error {")
+		if start < 0 {
+			t.Fatalf("Validate of the Credit-Control request missing:\n%s", converter)
+		}
+		return converter[start : start+strings.Index(converter[start:], "\n}\n")]
+	}
+	v := validate(nil)
+	for _, check := range []string{
+		"if m.SessionId == \"\" {\n\t\treturn fmt.Errorf(\"missing required AVP Session-Id\")",
+		"if m.OriginHost == \"\" {",
+		"if m.OriginRealm == \"\" {",
+	} {
+		if !strings.Contains(v, check) {
+			t.Errorf("Validate lacks %q:\n%s", check, v)
+		}
+	}
+	if strings.Contains(v, "m.AuthApplicationId") {
+		t.Errorf("Validate rejects a zero Auth-Application-Id:\n%s", v)
+	}
+	v = validate(TypeMap{datatype.UTF8StringType: {"bytes", "bytes"}})
+	if !strings.Contains(v, "if len(m.SessionId) == 0 {") {
+		t.Errorf("Validate accepts an empty Session-Id mapped to bytes:\n%s", v)
+	}
+}
+
+func TestGenerateExcludeCommands(t *testing.T) {
+	d := extraDictionary(t, `<?xml version="1.0" encoding="UTF-8"?>
+<diameter>
+	<application id="9998" type="auth" name="Filtered">
+		<command code="9001" short="SU" name="Subscribe">
+			<request>
+				<rule avp="Session-Id" required="true" max="1"/>
+				<rule avp="Subscription-Id" required="false"/>
+			</request>
+			<answer>
+				<rule avp="Session-Id" required="true" max="1"/>
+			</answer>
+		</command>
+		<command code="9002" short="NO" name="Notify">
+			<request>
+				<rule avp="Session-Id" required="true" max="1"/>
+			</request>
+			<answer>
+				<rule avp="Session-Id" required="true" max="1"/>
+			</answer>
+		</command>
+	</application>
+</diameter>`)
+	excluded := map[string][]string{"9998": {"subscribe"}}
+	result, err := NewGenerator(d, Options{Interfaces: []string{"9998"}, ExcludeCommands: excluded}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range result.Files {
+		for _, m := range file.Messages {
+			names = append(names, m.Name)
+		}
+	}
+	// the grouped AVP and enum of the excluded command are not generated
+	if strings.Join(names, " ") != "FilteredNotifyRequestPB FilteredNotifyAnswerPB" {
+		t.Errorf("got messages %v, want those of Notify only", names)
+	}
+
+	// exclusion wins over inclusion
+	opts := Options{Interfaces: []string{"9998"}, Commands: map[string][]string{"9998": {"9001", "9002"}}, ExcludeCommands: excluded}
+	if result, err = NewGenerator(d, opts).Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(result.Files[0].Messages); n != 2 {
+		t.Errorf("got %d messages, want those of Notify", n)
+	}
+
+	opts.ExcludeCommands = map[string][]string{"9998": {"Unsubscribe"}}
+	if _, err := NewGenerator(d, opts).Generate(); err == nil || !strings.Contains(err.Error(), "Unsubscribe of 9998") {
+		t.Errorf("got error %v, want an unknown command error", err)
+	}
+}
+
+func TestGenerateEmptyEnum(t *testing.T) {
+	d := extraDictionary(t, `<?xml version="1.0" encoding="UTF-8"?>
+<diameter>
+	<application id="9997" type="auth" name="Bare">
+		<command code="9003" short="BA" name="Bare">
+			<request>
+				<rule avp="Session-Id" required="true" max="1"/>
+				<rule avp="Bare-Enum" required="true" max="1"/>
+			</request>
+			<answer>
+				<rule avp="Session-Id" required="true" max="1"/>
+			</answer>
+		</command>
+		<avp name="Bare-Enum" code="9100" must="M" may="P" must-not="V" may-encrypt="Y">
+			<data type="Enumerated"/>
+		</avp>
+	</application>
+</diameter>`)
+	result, err := NewGenerator(d, Options{Interfaces: []string{"9997"}}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := result.Files[0].Messages[0].Fields[1].(*GeneralField)
+	if field.DataType != "int32" || field.AvpType != datatype.EnumeratedType {
+		t.Errorf("got %s field of AVP type %d, want an int32 field keeping the Enumerated AVP type", field.DataType, field.AvpType)
+	}
+	if !strings.Contains(string(result.Converters[0].Content), "datatype.Enumerated(m.BareEnum)") {
+		t.Errorf("Bare-Enum not encoded as Enumerated:\n%s", result.Converters[0].Content)
+	}
+}
+
+func TestGenerateUnnamedEnum(t *testing.T) {
+	d := extraDictionary(t, `<?xml version="1.0" encoding="UTF-8"?>
+<diameter>
+	<application id="9997" type="auth" name="Bare">
+		<command code="9003" short="BA" name="Bare">
+			<request>
+				<rule avp="Session-Id" required="true" max="1"/>
+				<rule avp="***" required="true" max="1"/>
+			</request>
+			<answer>
+				<rule avp="Session-Id" required="true" max="1"/>
+			</answer>
+		</command>
+		<avp name="***" code="9101" must="M" may="P" must-not="V" may-encrypt="Y">
+			<data type="Enumerated">
+				<item code="1" name="ONE"/>
+			</data>
+		</avp>
+	</application>
+</diameter>`)
+	result, err := NewGenerator(d, Options{Interfaces: []string{"9997"}}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := result.Files[0].Messages[0].Fields[1].(*GeneralField)
+	if field.VarName != "avp9101" || field.DataType != "Avp9101Enum" {
+		t.Errorf("got field %s of type %s, want avp9101 of type Avp9101Enum", field.VarName, field.DataType)
+	}
+	common := string(result.Protos[1].Content)
+	if !strings.Contains(common, "AVP_9101_UNSPECIFIED = 0;") || !strings.Contains(common, "AVP_9101_ONE = 1;") {
+		t.Errorf("enum values not named after the AVP code:\n%s", common)
+	}
+}
+
+func TestGenerateCycles(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict", "../testdata/cycle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	result, err := NewGenerator(d, Options{Interfaces: []string{"9995"}}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each grouped type is a single message, referring to itself or to the
+	// other member of the cycle
+	messages := make(map[string][]CompositeField)
+	for _, file := range result.Files {
+		for _, m := range file.Messages {
+			messages[m.Name] = append(messages[m.Name], m)
+		}
+	}
+	for name, field := range map[string]string{"Node": "node", "Left": "right", "Right": "left"} {
+		if len(messages[name]) != 1 {
+			t.Errorf("got %d messages %s, want 1", len(messages[name]), name)
+			continue
+		}
+		found := false
+		for _, f := range messages[name][0].Fields {
+			if f, ok := f.(*GeneralField); ok && f.VarName == field {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("message %s lacks field %s", name, field)
+		}
+	}
+
+	// Node and Right, referenced by both the request and the answer, are
+	// built once, so that each cycle is only detected once
+	if n := strings.Count(logs.String(), "refers to itself"); n != 2 {
+		t.Errorf("got %d cycles detected, want 2:\n%s", n, logs.String())
+	}
+}
@@ -0,0 +1,134 @@
+package diamproto
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+)
+
+// wildcardAVP is the name used by rules of the *[ AVP ] extension point.
+const wildcardAVP = "AVP"
+
+// LintIssue is a problem found in the loaded dictionaries.
+type LintIssue struct {
+	File    string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.File, i.Message)
+}
+
+// Lint checks the applications of the dictionary, or only the selected ones
+// if selected is not nil, for rules referencing unknown AVPs or AVPs only
+// found by a global scan, conflicting AVP definitions, empty enums and
+// inconsistent data.
+func (d *Dictionary) Lint(selected map[uint32]bool) []LintIssue {
+	var issues []LintIssue
+	seen := make(map[string]bool)
+	report := func(file, format string, args ...interface{}) {
+		issue := LintIssue{File: file, Message: fmt.Sprintf(format, args...)}
+		if !seen[issue.String()] {
+			seen[issue.String()] = true
+			issues = append(issues, issue)
+		}
+	}
+
+	checkRules := func(app *dict.App, owner string, rules []*dict.Rule, file string) {
+		vendorId := uint32(dict.UndefinedVendorID)
+		if len(app.Vendor) > 0 {
+			vendorId = app.Vendor[0].ID
+		}
+		for _, r := range rules {
+			if r.AVP == wildcardAVP {
+				continue
+			}
+			avp, level, _ := d.lookup(app.ID, vendorId, r.AVP)
+			switch level {
+			case notFound:
+				report(file, "%s references unknown AVP %s", owner, r.AVP)
+			case foundGlobally:
+				report(file, "%s references AVP %s only found by global scan, using code %d vendor %d from %s",
+					owner, r.AVP, avp.Code, avp.VendorID, d.sources[avp])
+			}
+			if r.Max != 0 && r.Min > r.Max {
+				report(file, "%s rule for %s has min %d greater than max %d", owner, r.AVP, r.Min, r.Max)
+			}
+		}
+	}
+
+	for _, app := range d.P.Apps() {
+		if selected != nil && !selected[app.ID] {
+			continue
+		}
+		file := d.appSources[app]
+		for _, command := range app.Command {
+			checkRules(app, fmt.Sprintf("Application %d command %s request", app.ID, command.Name), command.Request.Rule, file)
+			checkRules(app, fmt.Sprintf("Application %d command %s answer", app.ID, command.Name), command.Answer.Rule, file)
+		}
+		for _, avp := range app.AVP {
+			owner := fmt.Sprintf("AVP %s (%d)", avp.Name, avp.Code)
+			switch avp.Data.Type {
+			case datatype.EnumeratedType:
+				if len(avp.Data.Enum) == 0 {
+					report(file, "%s is Enumerated without values", owner)
+				}
+			case datatype.GroupedType:
+				if len(avp.Data.Rule) == 0 {
+					report(file, "%s is Grouped without rules", owner)
+				}
+				checkRules(app, owner, avp.Data.Rule, file)
+			}
+			if avp.Data.Type != datatype.EnumeratedType && len(avp.Data.Enum) > 0 {
+				report(file, "%s of type %s has enum values", owner, avp.Data.TypeName)
+			}
+			if avp.Data.Type != datatype.GroupedType && len(avp.Data.Rule) > 0 {
+				report(file, "%s of type %s has rules", owner, avp.Data.TypeName)
+			}
+		}
+	}
+
+	d.lintDefinitions(selected, report)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].File < issues[j].File
+	})
+	return issues
+}
+
+// lintDefinitions reports AVP codes defined with different names or data
+// types, and AVP names defined with different codes or data types.
+func (d *Dictionary) lintDefinitions(selected map[uint32]bool, report func(file, format string, args ...interface{})) {
+	type codeKey struct{ code, vendorId uint32 }
+	byCode := make(map[codeKey]*dict.AVP)
+	byName := make(map[string]*dict.AVP)
+	for _, app := range d.P.Apps() {
+		if selected != nil && !selected[app.ID] {
+			continue
+		}
+		for _, avp := range app.AVP {
+			key := codeKey{avp.Code, avp.VendorID}
+			if other, ok := byCode[key]; ok {
+				if other.Name != avp.Name {
+					report(d.sources[avp], "AVP code %d vendor %d defined as %s, already defined as %s in %s",
+						avp.Code, avp.VendorID, avp.Name, other.Name, d.sources[other])
+				} else if other.Data.TypeName != avp.Data.TypeName {
+					report(d.sources[avp], "AVP %s (%d) has type %s, already defined with type %s in %s",
+						avp.Name, avp.Code, avp.Data.TypeName, other.Data.TypeName, d.sources[other])
+				}
+			} else {
+				byCode[key] = avp
+			}
+			if other, ok := byName[avp.Name]; ok {
+				if other.Code != avp.Code || other.VendorID != avp.VendorID {
+					report(d.sources[avp], "AVP %s has code %d vendor %d, already defined with code %d vendor %d in %s",
+						avp.Name, avp.Code, avp.VendorID, other.Code, other.VendorID, d.sources[other])
+				}
+			} else {
+				byName[avp.Name] = avp
+			}
+		}
+	}
+}
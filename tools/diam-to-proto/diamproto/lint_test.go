@@ -0,0 +1,35 @@
+package diamproto
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	d, err := LoadDictionary("../testdata/dict", "../testdata/lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const file = "../testdata/lint/lint.xml"
+	want := []LintIssue{
+		{file, "Application 9994 command Check request references unknown AVP Missing-AVP"},
+		{file, "Application 9994 command Check request references AVP QoS-Class-Identifier only found by global scan, using code 1028 vendor 10415 from ../testdata/dict/gx.xml"},
+		{file, "AVP Empty-Enum (9300) is Enumerated without values"},
+		{file, "AVP code 263 vendor 0 defined as Lint-Session-Id, already defined as Session-Id in ../testdata/dict/base.xml"},
+		{file, "AVP Origin-Host has code 9301 vendor 0, already defined with code 264 vendor 0 in ../testdata/dict/base.xml"},
+		{file, "AVP Origin-Realm (296) has type UTF8String, already defined with type DiameterIdentity in ../testdata/dict/base.xml"},
+	}
+	got := d.Lint(nil)
+	if len(got) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("issue %d:\ngot  %s\nwant %s", i, got[i], want[i])
+		}
+	}
+
+	if got := d.Lint(map[uint32]bool{16777238: true}); len(got) != 0 {
+		t.Errorf("got %d issues for the Gx application: %v", len(got), got)
+	}
+}
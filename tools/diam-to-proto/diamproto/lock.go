@@ -0,0 +1,205 @@
+package diamproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LockFile records the field number assigned to every (message, AVP code,
+// vendor) triple, so that regenerating from updated dictionaries keeps the wire format
+// of already published messages.
+type LockFile struct {
+	Messages map[string]*LockedMessage `json:"messages"`
+}
+
+type LockedMessage struct {
+	Fields   []LockedField `json:"fields"`
+	Reserved []LockedField `json:"reserved,omitempty"`
+}
+
+type LockedField struct {
+	Code   uint32 `json:"code"`
+	Vendor uint32 `json:"vendor,omitempty"`
+	Name   string `json:"name"`
+	Number int    `json:"number"`
+}
+
+// lockKey identifies the AVP of a locked field.
+type lockKey struct {
+	code, vendor uint32
+}
+
+func (f LockedField) key() lockKey {
+	return lockKey{f.Code, f.Vendor}
+}
+
+// ReadLockFile loads the lock file at path. A missing file yields an empty
+// lock which is populated by the current run.
+func ReadLockFile(path string) (*LockFile, error) {
+	lock := &LockFile{Messages: make(map[string]*LockedMessage)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if lock.Messages == nil {
+		lock.Messages = make(map[string]*LockedMessage)
+	}
+	return lock, nil
+}
+
+func (l *LockFile) Write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// apply renumbers the fields of v with the numbers recorded in the lock.
+// Fields unknown to the lock get numbers above any number ever used by the
+// message, and locked fields missing from v become reserved. The lock is
+// updated to reflect the result.
+//
+// Fields are matched by AVP code and vendor. Entries of lock files written
+// before vendors were recorded have vendor 0, and are still claimed by the
+// first unmatched field with their code.
+func (l *LockFile) apply(v *CompositeField, format string) {
+	locked, ok := l.Messages[v.Name]
+	if !ok {
+		locked = &LockedMessage{}
+		l.Messages[v.Name] = locked
+	}
+
+	known := make(map[lockKey]LockedField)
+	used := make(map[int]bool)
+	next := 1
+	for _, f := range append(locked.Fields, locked.Reserved...) {
+		known[f.key()] = f
+		used[f.Number] = true
+		if f.Number >= next {
+			next = f.Number + 1
+		}
+	}
+
+	var fields []LockedField
+	present := make(map[lockKey]bool)
+	claim := func(field *GeneralField, lf LockedField) {
+		field.SetIndex(lf.Number)
+		present[lf.key()] = true
+		fields = append(fields, LockedField{Code: field.AvpCode, Vendor: field.VendorId, Name: field.VarName, Number: lf.Number})
+	}
+	var unmatched, added []*GeneralField
+	for _, f := range v.Fields {
+		field, ok := f.(*GeneralField)
+		if !ok {
+			continue
+		}
+		key := lockKey{field.AvpCode, field.VendorId}
+		if lf, ok := known[key]; ok && !present[key] {
+			claim(field, lf)
+			continue
+		}
+		unmatched = append(unmatched, field)
+	}
+	for _, field := range unmatched {
+		legacy := lockKey{field.AvpCode, 0}
+		if lf, ok := known[legacy]; ok && !present[legacy] {
+			claim(field, lf)
+			continue
+		}
+		added = append(added, field)
+	}
+	for _, field := range added {
+		number := next
+		if format == "avpcode" && !used[int(field.AvpCode)] {
+			number = int(field.AvpCode)
+		}
+		used[number] = true
+		if number >= next {
+			next = number + 1
+		}
+		field.SetIndex(number)
+		fields = append(fields, LockedField{Code: field.AvpCode, Vendor: field.VendorId, Name: field.VarName, Number: number})
+	}
+
+	var reserved []LockedField
+	for _, f := range append(locked.Fields, locked.Reserved...) {
+		if !present[f.key()] {
+			reserved = append(reserved, f)
+		}
+	}
+
+	sortLockedFields(fields)
+	sortLockedFields(reserved)
+	locked.Fields = fields
+	locked.Reserved = reserved
+	v.Reserved = reserved
+
+	sort.SliceStable(v.Fields, func(i, j int) bool {
+		return fieldIndex(v.Fields[i]) < fieldIndex(v.Fields[j])
+	})
+}
+
+func sortLockedFields(fields []LockedField) {
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Number < fields[j].Number
+	})
+}
+
+func fieldIndex(f Field) int {
+	if field, ok := f.(*GeneralField); ok {
+		return field.Index
+	}
+	return 0
+}
+
+// reservedStatements renders the reserved numbers, collapsed into ranges,
+// and the reserved names of a message. Names still used by a field of the
+// message, e.g. after an AVP code change, are not reserved.
+func reservedStatements(c CompositeField) []string {
+	reserved := c.Reserved
+	if len(reserved) == 0 {
+		return nil
+	}
+	var ranges, names []string
+	for i := 0; i < len(reserved); {
+		j := i
+		for j+1 < len(reserved) && reserved[j+1].Number == reserved[j].Number+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("%d", reserved[i].Number))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d to %d", reserved[i].Number, reserved[j].Number))
+		}
+		i = j + 1
+	}
+	seen := make(map[string]bool)
+	for _, f := range c.Fields {
+		if field, ok := f.(*GeneralField); ok {
+			seen[field.VarName] = true
+		}
+	}
+	for _, f := range reserved {
+		if !seen[f.Name] {
+			seen[f.Name] = true
+			names = append(names, fmt.Sprintf("%q", f.Name))
+		}
+	}
+	statements := []string{fmt.Sprintf("reserved %s;", strings.Join(ranges, ", "))}
+	if len(names) > 0 {
+		statements = append(statements, fmt.Sprintf("reserved %s;", strings.Join(names, ", ")))
+	}
+	return statements
+}
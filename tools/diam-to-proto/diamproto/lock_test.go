@@ -0,0 +1,91 @@
+package diamproto
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lockMessage returns a message with a field per code and vendor pair,
+// numbered sequentially as by the generator.
+func lockMessage(avps ...[2]uint32) CompositeField {
+	m := CompositeField{Name: "Msg", ProtoDataType: "message"}
+	for i, avp := range avps {
+		name := fmt.Sprintf("f%d_%d", avp[0], avp[1])
+		m.Fields = append(m.Fields, &GeneralField{Index: i + 1, DataType: "string", VarName: name, AvpCode: avp[0], VendorId: avp[1]})
+	}
+	return m
+}
+
+func indexes(m CompositeField) map[[2]uint32]int {
+	numbers := make(map[[2]uint32]int)
+	for _, f := range m.Fields {
+		field := f.(*GeneralField)
+		numbers[[2]uint32{field.AvpCode, field.VendorId}] = field.Index
+	}
+	return numbers
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock.json")
+	lock, err := ReadLockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := lockMessage([2]uint32{263, 0}, [2]uint32{1, 0}, [2]uint32{1, 10415}, [2]uint32{8, 0})
+	lock.apply(&first, NumberSeq)
+	if err := lock.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	want := indexes(first)
+	if want[[2]uint32{1, 0}] == want[[2]uint32{1, 10415}] {
+		t.Fatalf("AVPs of the same code and different vendors share number %d", want[[2]uint32{1, 0}])
+	}
+
+	// a rule is inserted before the others and another one removed
+	if lock, err = ReadLockFile(path); err != nil {
+		t.Fatal(err)
+	}
+	second := lockMessage([2]uint32{55, 0}, [2]uint32{263, 0}, [2]uint32{1, 10415}, [2]uint32{1, 0})
+	lock.apply(&second, NumberSeq)
+	got := indexes(second)
+	for _, avp := range [][2]uint32{{263, 0}, {1, 0}, {1, 10415}} {
+		if got[avp] != want[avp] {
+			t.Errorf("AVP %v renumbered from %d to %d", avp, want[avp], got[avp])
+		}
+	}
+	if got[[2]uint32{55, 0}] != 5 {
+		t.Errorf("got number %d for the new AVP, want 5 appended after the locked ones", got[[2]uint32{55, 0}])
+	}
+	statements := strings.Join(reservedStatements(second), "\n")
+	if statements != "reserved 4;\nreserved \"f8_0\";" {
+		t.Errorf("got reserved statements %q, want number 4 and name f8_0", statements)
+	}
+
+	// a removed AVP gets its reserved number back when it returns
+	third := lockMessage([2]uint32{8, 0})
+	lock.apply(&third, NumberSeq)
+	if got := indexes(third)[[2]uint32{8, 0}]; got != 4 {
+		t.Errorf("got number %d for the restored AVP, want 4", got)
+	}
+}
+
+func TestLockFileLegacy(t *testing.T) {
+	// entries written before vendors were recorded
+	lock := &LockFile{Messages: map[string]*LockedMessage{"Msg": {Fields: []LockedField{
+		{Code: 1016, Name: "f1016_10415", Number: 3},
+		{Code: 263, Name: "f263_0", Number: 1},
+	}}}}
+	m := lockMessage([2]uint32{1016, 10415}, [2]uint32{263, 0})
+	lock.apply(&m, NumberSeq)
+	if got := indexes(m); got[[2]uint32{1016, 10415}] != 3 || got[[2]uint32{263, 0}] != 1 {
+		t.Errorf("legacy entries not reused: %v", got)
+	}
+	if f := lock.Messages["Msg"].Fields[1]; f.Code != 1016 || f.Vendor != 10415 {
+		t.Errorf("legacy entry not updated with the vendor: %+v", f)
+	}
+	if len(m.Reserved) != 0 {
+		t.Errorf("unexpected reserved fields %v", m.Reserved)
+	}
+}
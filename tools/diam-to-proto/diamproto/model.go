@@ -0,0 +1,167 @@
+package diamproto
+
+import (
+	"fmt"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+)
+
+// CompositeField is a generated message or enum, holding GeneralField or
+// EnumField fields respectively.
+type CompositeField struct {
+	Priority      int
+	Name          string
+	ProtoDataType string
+	Fields        []Field
+	Reserved      []LockedField
+	Source        string
+	// set for the request and answer messages of a command only
+	AppId       uint32
+	CommandCode uint32
+	Command     string
+	Request     bool
+	// whether AVPs without a field are kept in an unknown_avps field
+	UnknownAvps bool
+}
+
+// Service is the gRPC service of an application, with a method per command
+// taking the request message and returning the answer message.
+type Service struct {
+	Name    string
+	Methods []Method
+}
+
+// Method is the rpc of a command.
+type Method struct {
+	Name     string
+	Request  string
+	Response string
+}
+
+// Envelope is the message pairing the Diameter header of a command with its
+// request or answer message, held by a body oneof.
+type Envelope struct {
+	Name    string
+	Request string
+	Answer  string
+}
+
+// Field is a field of a message or a value of an enum.
+type Field interface {
+	GetCode() uint32
+	SetIndex(int)
+}
+
+// EnumField is a value of a generated enum.
+type EnumField struct {
+	Name string
+	Code int32
+}
+
+func (f *EnumField) GetCode() uint32 {
+	return uint32(f.Code)
+}
+
+func (f *EnumField) SetIndex(int) {
+}
+
+func (f *EnumField) String() string {
+	return fmt.Sprintf("\t%s = %d;", f.Name, f.Code)
+}
+
+// GeneralField is a field of a generated message, built from a rule of a
+// command or grouped AVP.
+type GeneralField struct {
+	Index         int
+	DataType      string
+	VarName       string
+	AvpCode       uint32
+	VendorId      uint32
+	AvpType       datatype.TypeID
+	Mandatory     bool
+	Protected     bool
+	JsonFieldName string
+	Source        string
+	Comment       string
+	// proto file declaring DataType, for types set by an Override
+	Import string
+	// oneof the field is part of, if any, which must be set when the rule of
+	// one of its members was required
+	Oneof         string
+	OneofRequired bool
+	Repeated      bool
+	Required      bool
+	// occurrences allowed by the rule, Max being 0 if unbounded
+	Min int
+	Max int
+	// proto3 optional scalar with explicit presence
+	Optional bool
+	Nonnull  bool
+}
+
+func (f *GeneralField) GetCode() uint32 {
+	return f.AvpCode
+}
+
+func (f *GeneralField) SetIndex(i int) {
+	f.Index = i
+}
+
+// options renders the diameter/options.proto annotations of the field.
+func (f *GeneralField) options() string {
+	s := fmt.Sprintf(", (diameter.avp_code) = %d", f.AvpCode)
+	if f.VendorId != 0 {
+		s += fmt.Sprintf(", (diameter.vendor_id) = %d", f.VendorId)
+	}
+	if f.Mandatory {
+		s += ", (diameter.mandatory) = true"
+	}
+	if f.Protected {
+		s += ", (diameter.protected) = true"
+	}
+	if f.Required {
+		s += ", (diameter.required) = true"
+	}
+	if f.Min > f.minImplied() {
+		s += fmt.Sprintf(", (diameter.min) = %d", f.Min)
+	}
+	if f.Repeated && f.Max > 1 {
+		s += fmt.Sprintf(", (diameter.max) = %d", f.Max)
+	}
+	return s + fmt.Sprintf(", (diameter.avp_type) = \"%s\"", dataTypeNames[f.AvpType])
+}
+
+// minImplied returns the minimum number of occurrences implied by the field
+// being required.
+func (f *GeneralField) minImplied() int {
+	if f.Required {
+		return 1
+	}
+	return 0
+}
+
+// MinOccurs returns the minimum number of occurrences of the AVP.
+func (f *GeneralField) MinOccurs() int {
+	if f.Min > f.minImplied() {
+		return f.Min
+	}
+	return f.minImplied()
+}
+
+func (f *GeneralField) String() string {
+	s := "\t"
+	nullExtension := ""
+	if f.Repeated {
+		s += "repeated "
+	} else if f.Optional {
+		s += "optional "
+	}
+	if f.Nonnull {
+		nullExtension = ", (gogoproto.nullable) = false"
+	}
+	s += fmt.Sprintf("%s %s = %d [json_name = \"%s\"%s%s];", f.DataType, f.VarName, f.Index, f.JsonFieldName, f.options(), nullExtension)
+	if f.Comment != "" {
+		s += " // " + f.Comment
+	}
+	return s
+}
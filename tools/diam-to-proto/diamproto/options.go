@@ -0,0 +1,108 @@
+package diamproto
+
+import (
+	"fmt"
+	"path"
+
+	"tools/transcoder"
+)
+
+// optionsImport is the proto file declaring the custom options annotating
+// generated fields and messages with their Diameter mapping.
+const optionsImport = "diameter/options.proto"
+
+// optionsDeclarations extends the descriptor options with the Diameter
+// metadata of AVPs and commands, so that runtimes can discover the mapping
+// from descriptors. Extension numbers are in the range reserved for use
+// within an organization, and are read by the transcoder package.
+var optionsDeclarations = fmt.Sprintf(`extend google.protobuf.FieldOptions {
+	// AVP code of the field
+	uint32 avp_code = %d;
+	// Vendor-Id of the AVP, 0 if the V flag is not set
+	uint32 vendor_id = %d;
+	// whether the M flag is set
+	bool mandatory = %d;
+	// whether the P flag is set
+	bool protected = %d;
+	// Diameter data type of the AVP, e.g. Unsigned32 or Grouped
+	string avp_type = %d;
+	// whether the AVP is required, so encoded even if it has the zero value
+	bool required = %d;
+	// minimum number of occurrences, when more than required implies
+	uint32 min = %d;
+	// maximum number of occurrences of a repeated AVP, unbounded if unset
+	uint32 max = %d;
+}
+
+extend google.protobuf.MessageOptions {
+	// Application-Id of a command message
+	uint32 application_id = %d;
+	// command code of a command message
+	uint32 command_code = %d;
+	// whether a command message is the request, with the R flag set
+	bool is_request = %d;
+}
+`, transcoder.AvpCodeOption, transcoder.VendorIdOption, transcoder.MandatoryOption, transcoder.ProtectedOption,
+	transcoder.AvpTypeOption, transcoder.RequiredOption, transcoder.MinOption, transcoder.MaxOption,
+	transcoder.ApplicationIdOption, transcoder.CommandCodeOption, transcoder.IsRequestOption)
+
+// unknownAvpsNumber is the number of the unknown_avps field, the largest
+// field number, so that it never collides with the numbers of AVP fields.
+const unknownAvpsNumber = 1<<29 - 1
+
+// rawAvpDeclaration is the message of the unknown_avps fields, which the
+// transcoder package recognizes by its full name diameter.RawAvp.
+const rawAvpDeclaration = `
+// AVP without a field in the message it was received in, kept so that it is
+// sent again when the message is encoded
+message RawAvp {
+	uint32 code = 1;
+	uint32 vendor_id = 2;
+	// V, M and P flags as received
+	uint32 flags = 3;
+	// data of the AVP, without header and padding
+	bytes data = 4;
+}
+`
+
+// headerDeclaration is the message of the header field of envelopes, which
+// the transcoder package recognizes by its full name diameter.DiameterHeader.
+const headerDeclaration = `
+// header of a Diameter message, along with its Session-Id for routing
+message DiameterHeader {
+	uint32 application_id = 1;
+	uint32 command_code = 2;
+	// R, P, E and T command flags
+	bool request = 3;
+	bool proxiable = 4;
+	bool error = 5;
+	bool retransmitted = 6;
+	uint32 hop_by_hop_id = 7;
+	uint32 end_to_end_id = 8;
+	// Session-Id AVP of the message, empty if it has none
+	string session_id = 9;
+}
+`
+
+// newOptionsFile returns diameter/options.proto, which declares RawAvp and
+// DiameterHeader along with the options whatever the generation settings, so
+// that the file, and its Go package, can be shared by several generated
+// packages.
+func newOptionsFile(goPackage string) *ProtoFile {
+	return &ProtoFile{
+		Name:         optionsImport,
+		Pkg:          "diameter",
+		GoPackage:    goPackage,
+		Deps:         []string{"google/protobuf/descriptor.proto"},
+		Declarations: optionsDeclarations + rawAvpDeclaration + headerDeclaration,
+	}
+}
+
+// optionsGoPackage returns the go_package option of diameter/options.proto,
+// a diameter package next to the generated one unless set explicitly.
+func optionsGoPackage(opts Options) string {
+	if opts.OptionsGoPackage != "" || opts.GoPackage == "" {
+		return opts.OptionsGoPackage
+	}
+	return path.Join(path.Dir(goImportPath(opts.GoPackage)), "diameter")
+}
@@ -0,0 +1,234 @@
+package diamproto
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// wellKnownImports maps the external message types a generated field may
+// reference to the proto file declaring them.
+var wellKnownImports = map[string]string{
+	"google.protobuf.DoubleValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.FloatValue":  "google/protobuf/wrappers.proto",
+	"google.protobuf.Int64Value":  "google/protobuf/wrappers.proto",
+	"google.protobuf.UInt64Value": "google/protobuf/wrappers.proto",
+	"google.protobuf.Int32Value":  "google/protobuf/wrappers.proto",
+	"google.protobuf.UInt32Value": "google/protobuf/wrappers.proto",
+	"google.protobuf.BoolValue":   "google/protobuf/wrappers.proto",
+	"google.protobuf.StringValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.BytesValue":  "google/protobuf/wrappers.proto",
+	"google.protobuf.Timestamp":   "google/protobuf/timestamp.proto",
+}
+
+const gogoprotoImport = "gogoproto/gogo.proto"
+
+type ProtoFile struct {
+	Name      string
+	Pkg       string
+	GoPackage string
+	Messages  []CompositeField
+	Envelopes []Envelope
+	Services  []Service
+	Deps      []string
+	// declarations written as is after the messages
+	Declarations string
+}
+
+// linkImports records, for every file, the other files of the set declaring
+// message or enum types its fields refer to.
+func linkImports(files []*ProtoFile) {
+	declaredIn := make(map[string]string)
+	for _, file := range files {
+		for _, m := range file.Messages {
+			declaredIn[m.Name] = file.Name
+		}
+	}
+	for _, file := range files {
+		set := make(map[string]bool)
+		for _, m := range file.Messages {
+			for _, f := range m.Fields {
+				field, ok := f.(*GeneralField)
+				if !ok {
+					continue
+				}
+				if name, ok := declaredIn[field.DataType]; ok && name != file.Name {
+					set[name] = true
+				}
+			}
+		}
+		file.Deps = file.Deps[:0]
+		for name := range set {
+			file.Deps = append(file.Deps, name)
+		}
+		sort.Strings(file.Deps)
+	}
+}
+
+// imports returns the sorted list of files declaring the types referenced by
+// the messages of this file.
+func (p *ProtoFile) imports() []string {
+	set := make(map[string]bool)
+	for _, file := range p.Deps {
+		set[file] = true
+	}
+	for _, m := range p.Messages {
+		for _, f := range m.Fields {
+			field, ok := f.(*GeneralField)
+			if !ok {
+				continue
+			}
+			if file, ok := wellKnownImports[field.DataType]; ok {
+				set[file] = true
+			}
+			if field.Import != "" {
+				set[field.Import] = true
+			}
+			if field.Nonnull {
+				set[gogoprotoImport] = true
+			}
+			set[optionsImport] = true
+		}
+		if m.UnknownAvps {
+			set[optionsImport] = true
+		}
+	}
+	if len(p.Envelopes) > 0 {
+		set[optionsImport] = true
+	}
+	var imports []string
+	for file := range set {
+		imports = append(imports, file)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+func (p *ProtoFile) String() string {
+	var b strings.Builder
+	b.WriteString("// Code generated by diam-to-proto. DO NOT EDIT.\n\n")
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %s;\n\n", p.Pkg)
+
+	if imports := p.imports(); len(imports) > 0 {
+		for _, file := range imports {
+			fmt.Fprintf(&b, "import \"%s\";\n", file)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "option go_package = \"%s\";\n\n", p.goPackageOrDefault())
+
+	for i, m := range p.Messages {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(m.String())
+	}
+	for i, envelope := range p.Envelopes {
+		if i > 0 || len(p.Messages) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(envelope.String())
+	}
+	for i, service := range p.Services {
+		if i > 0 || len(p.Messages) > 0 || len(p.Envelopes) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(service.String())
+	}
+	if p.Declarations != "" {
+		if len(p.Messages) > 0 || len(p.Envelopes) > 0 || len(p.Services) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(p.Declarations)
+	}
+	return b.String()
+}
+
+// goPackageOrDefault returns the go_package option, derived from the proto
+// package unless set explicitly. protoc-gen-go rejects import paths without
+// a slash, hence the relative path.
+func (p *ProtoFile) goPackageOrDefault() string {
+	if p.GoPackage != "" {
+		return p.GoPackage
+	}
+	return "./" + strings.ReplaceAll(p.Pkg, ".", "/")
+}
+
+func (c CompositeField) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s {\n", c.ProtoDataType, c.Name)
+	if c.ProtoDataType == "enum" && hasAliases(c) {
+		b.WriteString("\toption allow_alias = true;\n")
+	}
+	if c.CommandCode != 0 {
+		fmt.Fprintf(&b, "\toption (diameter.application_id) = %d;\n", c.AppId)
+		fmt.Fprintf(&b, "\toption (diameter.command_code) = %d;\n", c.CommandCode)
+		if c.Request {
+			b.WriteString("\toption (diameter.is_request) = true;\n")
+		}
+	}
+	for _, statement := range reservedStatements(c) {
+		fmt.Fprintf(&b, "\t%s\n", statement)
+	}
+	oneofs := make(map[string]bool)
+	for _, f := range c.Fields {
+		field, ok := f.(*GeneralField)
+		if !ok || field.Oneof == "" {
+			fmt.Fprintln(&b, f)
+			continue
+		}
+		// members are written together where the first one appears
+		if oneofs[field.Oneof] {
+			continue
+		}
+		oneofs[field.Oneof] = true
+		fmt.Fprintf(&b, "\toneof %s {\n", field.Oneof)
+		for _, f := range c.Fields {
+			if member, ok := f.(*GeneralField); ok && member.Oneof == field.Oneof {
+				fmt.Fprintf(&b, "\t%s\n", member)
+			}
+		}
+		b.WriteString("\t}\n")
+	}
+	if c.UnknownAvps {
+		fmt.Fprintf(&b, "\trepeated diameter.RawAvp unknown_avps = %d;\n", unknownAvpsNumber)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (e Envelope) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "message %s {\n", e.Name)
+	b.WriteString("\tdiameter.DiameterHeader header = 1;\n")
+	b.WriteString("\toneof body {\n")
+	fmt.Fprintf(&b, "\t\t%s request = 2;\n", e.Request)
+	fmt.Fprintf(&b, "\t\t%s answer = 3;\n", e.Answer)
+	b.WriteString("\t}\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (s Service) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "service %s {\n", s.Name)
+	for _, m := range s.Methods {
+		fmt.Fprintf(&b, "\trpc %s(%s) returns (%s);\n", m.Name, m.Request, m.Response)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// hasAliases reports whether several values of an enum share a code.
+func hasAliases(c CompositeField) bool {
+	codes := make(map[uint32]bool)
+	for _, f := range c.Fields {
+		if codes[f.GetCode()] {
+			return true
+		}
+		codes[f.GetCode()] = true
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package diamproto
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+)
+
+// ProtoType is the proto type generated for a Diameter data type, depending
+// on whether the AVP is required or optional.
+type ProtoType struct {
+	Required string
+	Optional string
+}
+
+// TypeMap maps Diameter data types to proto types.
+type TypeMap map[datatype.TypeID]ProtoType
+
+// protoTypes maps every go-diameter data type, except Enumerated and Grouped
+// which produce their own types, to a proto type. Entries can be overridden
+// with Options.TypeMap. Octets and addresses are bytes, as they need not be
+// valid UTF-8 and addresses may be of any family, e.g. E.164.
+var protoTypes = TypeMap{
+	datatype.UnknownType:          {"bytes", "bytes"},
+	datatype.AddressType:          {"bytes", "bytes"},
+	datatype.DiameterIdentityType: {"string", "string"},
+	datatype.DiameterURIType:      {"string", "string"},
+	datatype.Float32Type:          {"float", "google.protobuf.FloatValue"},
+	datatype.Float64Type:          {"double", "google.protobuf.DoubleValue"},
+	datatype.IPFilterRuleType:     {"string", "string"},
+	datatype.IPv4Type:             {"bytes", "bytes"},
+	datatype.IPv6Type:             {"bytes", "bytes"},
+	datatype.Integer32Type:        {"int32", "google.protobuf.Int32Value"},
+	datatype.Integer64Type:        {"int64", "google.protobuf.Int64Value"},
+	datatype.OctetStringType:      {"bytes", "bytes"},
+	datatype.QoSFilterRuleType:    {"string", "string"},
+	datatype.TimeType:             {"google.protobuf.Timestamp", "google.protobuf.Timestamp"},
+	datatype.UTF8StringType:       {"string", "string"},
+	datatype.Unsigned32Type:       {"uint32", "google.protobuf.UInt32Value"},
+	datatype.Unsigned64Type:       {"uint64", "google.protobuf.UInt64Value"},
+}
+
+// withDefaults returns the proto types of protoTypes overridden by those of m.
+func withDefaults(m TypeMap) TypeMap {
+	types := make(TypeMap)
+	for id, pt := range protoTypes {
+		types[id] = pt
+	}
+	for id, pt := range m {
+		types[id] = pt
+	}
+	return types
+}
+
+// dataTypeNames maps go-diameter data type IDs back to their dictionary
+// names. Unknown is only used by go-diameter for AVPs missing from the
+// dictionaries and is not part of datatype.Available.
+var dataTypeNames = func() map[datatype.TypeID]string {
+	names := map[datatype.TypeID]string{datatype.UnknownType: "Unknown"}
+	for name, id := range datatype.Available {
+		names[id] = name
+	}
+	return names
+}()
+
+// For returns the proto type of a Diameter data type, and false if the data
+// type is not mapped.
+func (m TypeMap) For(t datatype.TypeID, required bool) (string, bool) {
+	pt, ok := m[t]
+	if !ok {
+		return "", false
+	}
+	if required {
+		return pt.Required, true
+	}
+	return pt.Optional, true
+}
+
+// String renders the entries of m in the form accepted by Set.
+func (m TypeMap) String() string {
+	var entries []string
+	for id, pt := range m {
+		entries = append(entries, fmt.Sprintf("%s=%s:%s", dataTypeNames[id], pt.Required, pt.Optional))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// Set adds the comma separated entries of value, in the form
+// DataType=requiredType[:optionalType], so that a TypeMap can be used as a
+// flag.Value.
+func (m TypeMap) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		name, types, ok := strings.Cut(entry, "=")
+		if !ok || types == "" {
+			return fmt.Errorf("invalid type mapping %q, expected DataType=requiredType[:optionalType]", entry)
+		}
+		id, ok := dataTypeID(name)
+		if !ok {
+			return fmt.Errorf("unknown Diameter data type %q", name)
+		}
+		if id == datatype.EnumeratedType || id == datatype.GroupedType {
+			return fmt.Errorf("data type %s cannot be mapped", name)
+		}
+		required, optional, ok := strings.Cut(types, ":")
+		if !ok {
+			optional = required
+		}
+		for _, t := range []string{required, optional} {
+			if !holds(t, id) {
+				return fmt.Errorf("proto type %s cannot hold %s values", t, name)
+			}
+		}
+		m[id] = ProtoType{Required: required, Optional: optional}
+	}
+	return nil
+}
+
+// numericTypes are the proto scalars holding the values of numeric and
+// enumerated data types.
+var numericTypes = map[string]bool{
+	"int32": true, "int64": true, "uint32": true, "uint64": true, "float": true, "double": true,
+}
+
+// holds reports whether the proto type t can hold the values of the data type
+// id, so that converters can convert between them: numbers and enums need a
+// numeric scalar or wrapper, times a Timestamp and other data types a string
+// or bytes. Other messages, e.g. set by an Override, are not converted and
+// always accepted.
+func holds(t string, id datatype.TypeID) bool {
+	scalar := t
+	if w, ok := wrapperScalarTypes[t]; ok {
+		scalar = w[0]
+	}
+	if _, ok := goScalarTypes[scalar]; !ok && t != "google.protobuf.Timestamp" {
+		return !strings.HasPrefix(t, "google.protobuf.")
+	}
+	switch id {
+	case datatype.TimeType:
+		return t == "google.protobuf.Timestamp"
+	case datatype.Integer32Type, datatype.Integer64Type, datatype.Unsigned32Type, datatype.Unsigned64Type,
+		datatype.Float32Type, datatype.Float64Type, datatype.EnumeratedType:
+		return numericTypes[scalar]
+	case datatype.GroupedType:
+		return false
+	}
+	return scalar == "string" || scalar == "bytes"
+}
+
+func dataTypeID(name string) (datatype.TypeID, bool) {
+	for id, n := range dataTypeNames {
+		if strings.EqualFold(n, name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
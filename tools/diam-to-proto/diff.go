@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"tools/diamproto"
+)
+
+// runDiff implements the diff subcommand and returns the process exit
+// status: 1 if any breaking change was found, 0 otherwise.
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	oldFolders := &FlagSet{}
+	newFolders := &FlagSet{}
+	flags.Var(oldFolders, "a", "Comma separated list of folders of the old dictionaries")
+	flags.Var(newFolders, "b", "Comma separated list of folders of the new dictionaries")
+	intf := flags.String("intf", "", "Comma separated list of interface aliases, application names or IDs to compare (all if empty)")
+	asJSON := flags.Bool("json", false, "Print JSON instead of text")
+	configPath := flags.String("config", "", "Configuration file of the generation, whose numberFormat, lock, optional and typeMap settings classify changes")
+	numberFormat := flags.String("numberFormat", diamproto.NumberSeq, "Field number format of the generation: seq or avpcode")
+	lockPath := flags.String("lock", "", "Lock file of the generation, keeping field numbers stable (none if empty)")
+	optional := flags.Bool("optional", false, "Whether the generation emits proto3 optional scalars")
+	typeMap := diamproto.TypeMap{}
+	flags.Var(typeMap, "typeMap", "Comma separated type mappings of the generation: DataType=requiredType[:optionalType]")
+	flags.Parse(args)
+
+	if len(oldFolders.elements) == 0 || len(newFolders.elements) == 0 {
+		log.Printf("Both -a and -b folders are required")
+		return 1
+	}
+	a, err := diamproto.LoadDictionary(oldFolders.elements...)
+	if err != nil {
+		log.Printf("Failed to load old dictionaries: %s", err)
+		return 1
+	}
+	b, err := diamproto.LoadDictionary(newFolders.elements...)
+	if err != nil {
+		log.Printf("Failed to load new dictionaries: %s", err)
+		return 1
+	}
+
+	// changes are classified for the generation settings, flags overriding
+	// the configuration file
+	config := diamproto.DefaultConfig()
+	if *configPath != "" {
+		if config, err = diamproto.ReadConfig(*configPath); err != nil {
+			log.Printf("Failed to read config: %s", err)
+			return 1
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "numberFormat":
+			config.NumberFormat = *numberFormat
+		case "lock":
+			config.Lock = *lockPath
+		case "optional":
+			config.Optional = *optional
+		}
+	})
+	opts, err := config.Options()
+	if err != nil {
+		log.Printf("Invalid config: %s", err)
+		return 1
+	}
+	for id, pt := range typeMap {
+		opts.TypeMap[id] = pt
+	}
+	if config.Lock != "" {
+		if opts.Lock, err = diamproto.ReadLockFile(config.Lock); err != nil {
+			log.Printf("Failed to read lock file: %s", err)
+			return 1
+		}
+	}
+
+	var selected map[uint32]bool
+	if *intf != "" {
+		selected = make(map[uint32]bool)
+		for _, token := range strings.Split(*intf, ",") {
+			id, _, err := diamproto.ResolveApplication(b.P, token)
+			if err != nil {
+				if id, _, err = diamproto.ResolveApplication(a.P, token); err != nil {
+					log.Printf("Invalid interface: %s", err)
+					return 1
+				}
+			}
+			selected[id] = true
+		}
+	}
+
+	changes := diamproto.Diff(a, b, selected, opts)
+	breaking := 0
+	for _, c := range changes {
+		if c.Breaking {
+			breaking++
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			log.Printf("Failed to encode changes: %s", err)
+			return 1
+		}
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+		fmt.Printf("%d changes found, %d breaking\n", len(changes), breaking)
+	}
+	if breaking > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,413 +1,355 @@
-// go run generator.go -help
+// go run . -help
 // Usage of generator:
+//   -commands value
+//         Comma separated application:command pairs restricting the commands generated for an application, by command name or code
+//   -config string
+//         YAML or JSON configuration file, whose settings are overridden by flags
+//   -conflict string
+//         Resolution of types differing between applications: merge, namespace or fail (default "merge")
 //   -d value
 //         Comma separated list of folders to load (default ./dict)
+//   -envelopes
+//         Emit per command an envelope message pairing a diameter.DiameterHeader with the request or answer
+//   -excludeCommands value
+//         Comma separated application:command pairs of commands not to generate, by command name or code
+//   -goPackage string
+//         Value of the go_package option (defaults to the proto package)
+//   -goOut string
+//         Output directory for Go converters between go-diameter messages and generated protobufs (disabled if empty)
 //   -intf string
-//         Comma separated list (no spaces) of interfaces from [gx, gy, rx, sh, sy] (default "gx,gy")
+//         Comma separated list (no spaces) of interface aliases, application names or IDs (default "gx,gy")
+//   -lock string
+//         Lock file keeping field numbers stable across runs (disabled if empty)
 //   -numberFormat string
-//         Filed number format: seq or avpcode (default "seq")
-// Example: go run generator.go -d ./dict -d ./custom -intf gx,gy,rx
+//         Field number format: seq or avpcode (default "seq")
+//   -optional
+//         Emit proto3 optional scalars instead of wrapper types for AVPs that are not required
+//   -optionsGoPackage string
+//         Value of the go_package option of diameter/options.proto (defaults to a diameter package next to -goPackage)
+//   -out string
+//         Output directory for generated .proto files (stdout if empty)
+//   -package string
+//         Proto package name of generated files (default "diameterpb")
+//   -services
+//         Emit a gRPC service per application with a method per command
+//   -typeMap value
+//         Comma separated overrides of the proto type of a Diameter data type: DataType=requiredType[:optionalType]
+//   -unknownAvps
+//         Keep AVPs without a field in a repeated diameter.RawAvp unknown_avps field of every message
+// Fields and command messages are annotated with the AVP and command metadata
+// options declared by diameter/options.proto, generated along with the files
+// into a Go package of its own, which can be shared by several generated
+// packages.
+// Rule bounds other than the implied ones are carried by the min and max
+// options, and checked by the Validate method the converters give every message.
+// Example: go run . -d ./dict -d ./custom -intf gx,gy,rx -out ./proto
+//
+// A configuration file describes a whole run, paths being relative to the
+// file, and also selects commands and renames generated types and fields:
+//
+//	dictionaries: [./dict, ./custom]
+//	interfaces: [gx, sh]
+//	commands:
+//	  sh: [User-Data]        # command names or codes, all if missing
+//	excludeCommands:
+//	  gx: [Re-Auth]          # command names or codes not to generate
+//	out: ./proto
+//	goOut: ./diameterpb
+//	goPackage: example.com/diameterpb
+//	optionsGoPackage: example.com/diameter   # shared by the generated packages
+//	numberFormat: avpcode
+//	services: true
+//	unknownAvps: true
+//	envelopes: true
+//	typeMap:
+//	  UTF8String: bytes
+//	renames:
+//	  messages:
+//	    GxChargingControlCreditControlRequestPB: GxCCR
+//	  fields:
+//	    SubscriptionId.subscriptionIdData: data
+//	overrides:               # keyed by AVP name, code:vendor or code
+//	  QoS-Class-Identifier: {type: uint32, field: qci, jsonName: qci}
+//	  "1016:10415": {message: QoS}
+//	  3GPP-User-Location-Info: {type: example.Uli, import: example/uli.proto}
+//	oneofs:                  # alternative fields, by message then oneof name
+//	  SubscriptionId:
+//	    id: [subscriptionIdE164, subscriptionIdIMSI]
+//
+// Example: go run . -config ./diam-to-proto.yaml -out ./build/proto
+// Example: go run . -intf gx,sh -commands sh:User-Data -excludeCommands gx:258 -out ./proto
+//
+// go run . list -help
+// Usage of list:
+//   -d value
+//         Comma separated list of folders to load (default ./dict)
+//   -intf string
+//         Comma separated list of interface aliases, application names or IDs to list (all if empty)
+//   -json
+//         Print JSON instead of tables
+// Example: go run . list -d ./dict -intf gx -json
+//
+// go run . lint -help
+// Usage of lint:
+//   -d value
+//         Comma separated list of folders to load (default ./dict)
+//   -intf string
+//         Comma separated list of interface aliases, application names or IDs to lint (all if empty)
+// Exits with status 1 if any issue is reported.
+// Example: go run . lint -d ./dict -d ./custom
+//
+// go run . diff -help
+// Usage of diff:
+//   -a value
+//         Comma separated list of folders of the old dictionaries
+//   -b value
+//         Comma separated list of folders of the new dictionaries
+//   -config string
+//         Configuration file of the generation, whose numberFormat, lock, optional and typeMap settings classify changes
+//   -intf string
+//         Comma separated list of interface aliases, application names or IDs to compare (all if empty)
+//   -json
+//         Print JSON instead of text
+//   -lock string
+//         Lock file of the generation, keeping field numbers stable (none if empty)
+//   -numberFormat string
+//         Field number format of the generation: seq or avpcode (default "seq")
+//   -optional
+//         Whether the generation emits proto3 optional scalars
+//   -typeMap value
+//         Comma separated type mappings of the generation: DataType=requiredType[:optionalType]
+// Reports added, removed and changed applications, commands, AVPs, rules and
+// enum values, each classified as compatible or breaking for the protobufs
+// generated with the given settings: with seq numbering and no lock file,
+// adding or removing a rule before others renumbers their fields.
+// Exits with status 1 if any breaking change is reported.
+// Example: go run . diff -a ./dict-rel15 -b ./dict-rel16 -intf gx
+//
+// Output is deterministic for a given list of folders. The golden files of
+// testdata/golden are regenerated with: go test -update
+//
+// The commands are thin wrappers around the tools/diamproto package, which
+// can be used directly, e.g. from go generate tooling:
+//
+//	d, err := diamproto.LoadDictionary("./dict")
+//	...
+//	result, err := diamproto.NewGenerator(d, diamproto.Options{Interfaces: []string{"gx"}}).Generate()
+//	...
+//	for _, file := range result.Protos {
+//		err = file.Write("./proto")
+//	}
 
 package main
 
 import (
 	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"log"
-	"path/filepath"
-	"reflect"
+	"os"
 	"sort"
 	"strings"
-	"unicode"
 
-	"github.com/fiorix/go-diameter/v4/diam/datatype"
-	"github.com/fiorix/go-diameter/v4/diam/dict"
+	"tools/diamproto"
 )
 
-var fields []CompositeField
-var parsedFields map[string]CompositeField = make(map[string]CompositeField)
-
-var apps = map[string]uint32{
-	"gy": 4,
-	"sh": 16777217,
-	"rx": 16777236,
-	"gx": 16777238,
-	"sy": 16777303,
-}
-
-type newDictionaryBuilder interface {
-	load(paths *FlagSet) error
-	build(name string, priority int, node *Node) CompositeField
-	search(appId, vendorId uint32, code interface{}) (*dict.AVP, error)
-}
-
-type Dictionary struct {
-	P *dict.Parser
-	newDictionaryBuilder
-}
-
-type Node struct {
-	appId    uint32
-	rules    []*dict.Rule
-	vendorId uint32
+// FlagSet is an ordered list of folders given with repeated or comma
+// separated -d flags. Folders are loaded in the order given, replacing the
+// defaults the first time the flag is set.
+type FlagSet struct {
+	elements []string
+	set      bool
 }
 
-type FlagSet struct {
-	elements map[string]bool
+func newFolderFlag() *FlagSet {
+	return &FlagSet{elements: []string{"./dict"}}
 }
 
 func (l *FlagSet) String() string {
-	var names string
-	for name := range l.elements {
-		names += name
-	}
-	return names
+	return strings.Join(l.elements, ",")
 }
 
-func (l *FlagSet) Set(name string) error {
-	l.elements[name] = true
+func (l *FlagSet) Set(value string) error {
+	if !l.set {
+		l.elements, l.set = nil, true
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name == "" {
+			continue
+		}
+		duplicate := false
+		for _, element := range l.elements {
+			duplicate = duplicate || element == name
+		}
+		if !duplicate {
+			l.elements = append(l.elements, name)
+		}
+	}
 	return nil
 }
 
-func main() {
-
-	folders := &FlagSet{elements: map[string]bool{"./dict": true}}
-	intf := flag.String("intf", "gx,gy", "Comma separated list (no spaces) of interfaces from [gx, gy, rx, sh, sy]")
-	protoNumberFormat := flag.String("numberFormat", "seq", "Field number format: seq or avpcode")
-	flag.Var(folders, "d", "Comma separated list of folders to load")
-	flag.Parse()
+// commandsFlag is a list of commands per application given with repeated or
+// comma separated -commands or -excludeCommands flags, as application:command
+// pairs, e.g. sh:User-Data,sh:307. The flag replaces the list of the
+// configuration file the first time it is set.
+type commandsFlag struct {
+	commands *map[string][]string
+	set      bool
+}
 
-	var enabledApps = make(map[uint32]bool)
-	for _, id := range strings.Split(*intf, ",") {
-		enabledApps[apps[id]] = true
+func (c *commandsFlag) String() string {
+	if c.commands == nil {
+		return ""
 	}
-
-	dictionary := &Dictionary{}
-	if err := dictionary.load(folders); err != nil {
-		log.Fatalf("Failed to load dictionaries: %s", err)
+	var keys, pairs []string
+	for key := range *c.commands {
+		keys = append(keys, key)
 	}
-
-	var priority int = 0
-
-	for _, app := range dictionary.P.Apps() {
-		if enabledApps[app.ID] {
-			for _, command := range app.Command {
-				request := fmt.Sprintf("%s%s", app.Name, command.Name)
-				replacer := strings.NewReplacer("TGPP", "", " ", "", "-", "")
-				request = replacer.Replace(request)
-
-				var vendorId = uint32(dict.UndefinedVendorID)
-				if len(app.Vendor) > 0 {
-					vendorId = app.Vendor[0].ID
-				}
-				reqField := dictionary.build(request+"RequestPB", priority,
-					&Node{appId: app.ID, rules: command.Request.Rule, vendorId: vendorId},
-				)
-				fields = append(fields, reqField)
-				priority++
-				ansField := dictionary.build(request+"AnswerPB", priority,
-					&Node{appId: app.ID, rules: command.Answer.Rule, vendorId: vendorId},
-				)
-				fields = append(fields, ansField)
-				priority++
-			}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, command := range (*c.commands)[key] {
+			pairs = append(pairs, key+":"+command)
 		}
 	}
+	return strings.Join(pairs, ",")
+}
 
-	for _, parsedField := range parsedFields {
-		fields = append(fields, parsedField)
+func (c *commandsFlag) Set(value string) error {
+	if !c.set {
+		*c.commands, c.set = make(map[string][]string), true
 	}
-
-	sort.SliceStable(fields, func(i, j int) bool {
-		diff := fields[i].priority - fields[j].priority
-		if diff == 0 {
-			diff = len(fields[j].fields) - len(fields[i].fields)
-			if diff == 0 {
-				return fields[i].name < fields[j].name
-			}
-		}
-		return diff < 0
-	})
-
-	for _, v := range fields {
-		// fmt.Printf("%s %s {\n", v.protoDataType, v.name)
-		fmt.Printf("message %s {\n", v.name)
-		if v.protoDataType == "enum" {
-			fmt.Println("\tvalue Value = 1;")
-			fmt.Println("\tenum value {")
-		}
-
-		// ascending sort fields based on avp codes
-		if *protoNumberFormat == "avpcode" {
-			sort.SliceStable(v.fields, func(i, j int) bool {
-				return v.fields[i].GetCode() < v.fields[j].GetCode()
-			})
-		}
-
-		for i, f := range v.fields {
-			if *protoNumberFormat == "avpcode" {
-				f.SetIndex(int(f.GetCode()))
-			} else {
-				f.SetIndex(i + 1)
-			}
-			fmt.Println(f)
+	for _, pair := range strings.Split(value, ",") {
+		if pair == "" {
+			continue
 		}
-		if v.protoDataType == "enum" {
-			fmt.Println("\t}")
+		key, command, ok := strings.Cut(pair, ":")
+		if !ok || key == "" || command == "" {
+			return fmt.Errorf("%q is not an application:command pair", pair)
 		}
-		fmt.Println("}")
-		fmt.Println()
+		(*c.commands)[key] = append((*c.commands)[key], command)
 	}
+	return nil
 }
 
-func (d *Dictionary) load(paths *FlagSet) error {
-	if d.P == nil {
-		d.P, _ = dict.NewParser()
-	}
-	for path := range paths.elements {
-		log.Printf("Loading dictionaries from %s", path)
-		err := filepath.WalkDir(path, func(path string, info fs.DirEntry, err error) error {
-			if err != nil {
-				log.Println(err)
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-			dictErr := d.P.LoadFile(path)
-			if dictErr != nil {
-				log.Printf("Failed to load dictionary: %s: %s", path, dictErr)
-				return dictErr
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "list":
+			if err := runList(os.Args[2:], os.Stdout); err != nil {
+				log.Fatal(err)
 			}
-			return nil
-		})
-		if err != nil {
-			return err
+			return
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
 		}
 	}
-	return nil
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func (d *Dictionary) build(name string, priority int, node *Node) CompositeField {
-	composite := CompositeField{name: name, priority: priority, protoDataType: "message"}
-	for _, r := range node.rules {
-		avp, err := d.search(node.appId, node.vendorId, r.AVP)
-		if err != nil {
-			continue
-		}
-		typeName := kebabToCamelCase(avp.Name)
-		a := []rune(typeName)
-		a[0] = unicode.ToLower(a[0])
-		varName := string(a)
-		field := &GeneralField{
-			varName:       varName,
-			avpCode:       avp.Code,
-			jsonFieldName: avp.Name,
-			repeated:      r.Max != 1,
-			required:      r.Required,
-		}
-		switch avp.Data.Type {
-
-		case datatype.OctetStringType:
-			// field.dataType = "bytes"
-			field.dataType = "string"
-
-		case datatype.UTF8StringType, datatype.DiameterIdentityType,
-			datatype.AddressType, datatype.DiameterURIType, datatype.IPFilterRuleType:
-			field.dataType = "string"
+// generatorFlags holds the settings of a generation run, from the command
+// line and the configuration file.
+type generatorFlags struct {
+	opts     diamproto.Options
+	folders  *FlagSet
+	config   string
+	outDir   string
+	goOut    string
+	lockPath string
+}
 
-		case datatype.EnumeratedType:
-			if len(avp.Data.Enum) == 0 {
-				log.Panic("Enum with no values")
-				continue
-			}
-			field.dataType = typeName + "Enum"
-			enumField := processEnumField(field.dataType, avp.Data.Enum)
-			checkConflictAndResolve(field.dataType, enumField)
-		case datatype.GroupedType:
-			field.dataType = typeName
-			groupField := d.build(typeName, 50, &Node{appId: node.appId, rules: avp.Data.Rule, vendorId: node.vendorId})
-			checkConflictAndResolve(field.dataType, groupField)
-		case datatype.Unsigned32Type:
-			field.dataType = "uint32"
-			if !field.required {
-				field.dataType = "google.protobuf.UInt32Value"
-			}
-		case datatype.Unsigned64Type:
-			field.dataType = "uint64"
-			if !field.required {
-				field.dataType = "google.protobuf.UInt64Value"
-			}
-		case datatype.Integer32Type:
-			field.dataType = "int32"
-			if !field.required {
-				field.dataType = "google.protobuf.Int32Value"
-			}
-		case datatype.Integer64Type:
-			field.dataType = "int64"
-			if !field.required {
-				field.dataType = "google.protobuf.Int64Value"
-			}
-		case datatype.TimeType:
-			field.dataType = "google.protobuf.Timestamp"
-		default:
-			field.dataType = avp.Data.TypeName
-			log.Panicf("%s data type not supported yet", field.dataType)
-		}
-		composite.fields = append(composite.fields, field)
+// parseGeneratorFlags parses the command line using the settings of config
+// as defaults, so that flags override the configuration file.
+func parseGeneratorFlags(args []string, config *diamproto.Config) (*generatorFlags, error) {
+	opts, err := config.Options()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
-	return composite
+	f := &generatorFlags{opts: opts, folders: &FlagSet{elements: config.Dictionaries}}
+	flags := flag.NewFlagSet("generator", flag.ExitOnError)
+	flags.StringVar(&f.config, "config", "", "YAML or JSON configuration file, whose settings are overridden by flags")
+	intf := flags.String("intf", strings.Join(config.Interfaces, ","), "Comma separated list (no spaces) of interface aliases, application names or IDs")
+	flags.Var(&commandsFlag{commands: &f.opts.Commands}, "commands", "Comma separated application:command pairs restricting the commands generated for an application, by command name or code")
+	flags.Var(&commandsFlag{commands: &f.opts.ExcludeCommands}, "excludeCommands", "Comma separated application:command pairs of commands not to generate, by command name or code")
+	flags.StringVar(&f.opts.NumberFormat, "numberFormat", opts.NumberFormat, "Field number format: seq or avpcode")
+	flags.BoolVar(&f.opts.Optional, "optional", opts.Optional, "Emit proto3 optional scalars instead of wrapper types for AVPs that are not required")
+	flags.StringVar(&f.outDir, "out", config.Out, "Output directory for generated .proto files (stdout if empty)")
+	flags.BoolVar(&f.opts.Services, "services", opts.Services, "Emit a gRPC service per application with a method per command")
+	flags.BoolVar(&f.opts.UnknownAvps, "unknownAvps", opts.UnknownAvps, "Keep AVPs without a field in a repeated diameter.RawAvp unknown_avps field of every message")
+	flags.StringVar(&f.opts.Package, "package", opts.Package, "Proto package name of generated files")
+	flags.StringVar(&f.opts.GoPackage, "goPackage", opts.GoPackage, "Value of the go_package option (defaults to the proto package)")
+	flags.StringVar(&f.opts.OptionsGoPackage, "optionsGoPackage", opts.OptionsGoPackage, "Value of the go_package option of diameter/options.proto (defaults to a diameter package next to -goPackage)")
+	flags.BoolVar(&f.opts.Envelopes, "envelopes", opts.Envelopes, "Emit per command an envelope message pairing a diameter.DiameterHeader with the request or answer")
+	flags.StringVar(&f.goOut, "goOut", config.GoOut, "Output directory for Go converters between go-diameter messages and generated protobufs (disabled if empty)")
+	flags.StringVar(&f.lockPath, "lock", config.Lock, "Lock file keeping field numbers stable across runs (disabled if empty)")
+	flags.StringVar(&f.opts.Conflict, "conflict", opts.Conflict, "Resolution of types differing between applications: merge, namespace or fail")
+	flags.Var(f.folders, "d", "Comma separated list of folders to load")
+	flags.Var(f.opts.TypeMap, "typeMap", "Comma separated overrides of the proto type of a Diameter data type: DataType=requiredType[:optionalType]")
+	flags.Parse(args)
+	f.opts.Interfaces = strings.Split(*intf, ",")
+	return f, nil
 }
 
-func (d *Dictionary) search(appId, vendorId uint32, code interface{}) (*dict.AVP, error) {
-	var message = "+ Found AVP with VendorId [%s]"
-	avp, err := d.P.FindAVPWithVendor(appId, code, vendorId)
+// run generates the proto files, and optionally the Go converters, for the
+// given command line arguments. Files are printed to stdout unless an output
+// directory is set.
+func run(args []string, stdout io.Writer) error {
+	f, err := parseGeneratorFlags(args, diamproto.DefaultConfig())
 	if err != nil {
-		message = "- Failed to find AVP with VendorId [ %s ]"
-		avp, err = d.P.FindAVP(appId, code)
+		return err
+	}
+	if f.config != "" {
+		config, err := diamproto.ReadConfig(f.config)
 		if err != nil {
-			message = "-- Failed to find AVP without VendorId [ %s ]"
-			avp, err = d.P.ScanAVP(code)
-			if err != nil {
-				message = "--- Failed to find AVP globally [ %s ]"
-				// log.Fatalf("AVP [ %s ] not found !!!", code)
-			}
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		if f, err = parseGeneratorFlags(args, config); err != nil {
+			return err
 		}
 	}
-	log.Printf(message, code)
-	return avp, err
-}
+	opts := f.opts
 
-func processEnumField(name string, enums []*dict.Enum) CompositeField {
-	composite := CompositeField{name: name, priority: 10, protoDataType: "enum"}
-	if enums[0].Code != 0 {
-		first := strings.Split(enums[0].Name, "_")
-		second := strings.Split(enums[len(enums)-1].Name, "_")
-		var field *EnumField
-		if first[0] == second[0] {
-			field = &EnumField{name: fmt.Sprintf("_%s_UNDEFINED", first[0]), code: 0}
-		} else if first[len(first)-1] == second[len(second)-1] {
-			field = &EnumField{name: fmt.Sprintf("_UNDEFINED_%s", first[len(first)-1]), code: 0}
-		} else {
-			field = &EnumField{name: fmt.Sprintf("_%s_UNDEFINED", name), code: 0}
+	dictionary, err := diamproto.LoadDictionary(f.folders.elements...)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionaries: %w", err)
+	}
+
+	if f.lockPath != "" {
+		if opts.Lock, err = diamproto.ReadLockFile(f.lockPath); err != nil {
+			return fmt.Errorf("failed to read lock file: %w", err)
 		}
-		composite.fields = append(composite.fields, field)
 	}
-	for _, enum := range enums {
-		field := &EnumField{name: kebabToCamelCase(enum.Name), code: uint32(enum.Code)}
-		composite.fields = append(composite.fields, field)
+
+	result, err := diamproto.NewGenerator(dictionary, opts).Generate()
+	if err != nil {
+		return err
 	}
-	return composite
-}
 
-func checkConflictAndResolve(dataType string, compField CompositeField) {
-	if parsedField, ok := parsedFields[dataType]; ok {
-		log.Printf("Type %s already processed", dataType)
-		if !reflect.DeepEqual(compField, parsedField) {
-			log.Printf("*** Type %s has mismatching fields", dataType)
-			if len(compField.fields) > len(parsedField.fields) {
-				parsedFields[dataType] = compField
-			} else if len(compField.fields) == len(parsedField.fields) {
-				log.Fatalf("****** Type %s has deep mismatching fields. Needs manual intervention", dataType)
-			}
+	if opts.Lock != nil {
+		if err := opts.Lock.Write(f.lockPath); err != nil {
+			return fmt.Errorf("failed to write lock file: %w", err)
 		}
-		return
 	}
-	parsedFields[dataType] = compField
-}
 
-func kebabToCamelCase(kebab string) (camelCase string) {
-	isToUpper := false
-	isFirstLetter := true
-	for _, runeValue := range kebab {
-		if isFirstLetter {
-			isFirstLetter = false
-			// special case where protoc fails to compile enums whose names starting with numeric char, mainly '3' as in 3GPP
-			if string(runeValue) == "3" {
-				camelCase += "T"
-				continue
-			}
+	for _, file := range result.Protos {
+		if f.outDir == "" {
+			fmt.Fprintf(stdout, "// %s\n%s\n", file.Name, file.Content)
+			continue
 		}
-		if isToUpper {
-			camelCase += strings.ToUpper(string(runeValue))
-			isToUpper = false
-		} else {
-			if runeValue == '-' {
-				isToUpper = true
-			} else {
-				camelCase += string(runeValue)
-			}
+		if err := file.Write(f.outDir); err != nil {
+			return fmt.Errorf("failed to write proto file: %w", err)
 		}
 	}
-	return
-}
-
-type CompositeField struct {
-	priority      int
-	name          string
-	protoDataType string
-	fields        []Field
-}
-
-type Field interface {
-	GetCode() uint32
-	SetIndex(int)
-}
-
-type EnumField struct {
-	name string
-	code uint32
-}
-
-func (f *EnumField) GetCode() uint32 {
-	return f.code
-}
-
-func (f *EnumField) SetIndex(int) {
-}
-
-func (f *EnumField) String() string {
-	// return fmt.Sprintf("\t%s = %d;", f.name, f.code)
-	return fmt.Sprintf("\t\t%s = %d;", f.name, f.code)
-}
-
-type GeneralField struct {
-	index         int
-	dataType      string
-	varName       string
-	avpCode       uint32
-	jsonFieldName string
-	comment       string
-	isAlternative bool
-	repeated      bool
-	required      bool
-	nonnull       bool
-}
-
-func (f *GeneralField) GetCode() uint32 {
-	return f.avpCode
-}
-
-func (f *GeneralField) SetIndex(i int) {
-	f.index = i
-}
 
-func (f *GeneralField) String() string {
-	s := "\t"
-	nullExtension := ""
-	if f.isAlternative {
-		s += "// "
-	}
-	if f.repeated {
-		s += "repeated "
-	}
-	if f.nonnull {
-		nullExtension = ", (gogoproto.nullable) = false"
-	}
-	s += fmt.Sprintf("%s %s = %d [json_name = \"%s\"%s];", f.dataType, f.varName, f.index, f.jsonFieldName, nullExtension)
-	if f.comment != "" {
-		s += " // " + f.comment
+	if f.goOut != "" {
+		for _, file := range result.Converters {
+			if err := file.Write(f.goOut); err != nil {
+				return fmt.Errorf("failed to write Go converters: %w", err)
+			}
+		}
 	}
-	return s
+	return nil
 }
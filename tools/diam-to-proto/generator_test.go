@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files of testdata/golden")
+
+// goldenCases are generated from the sample dictionaries of testdata/dict and
+// compared with testdata/golden/<name>.
+var goldenCases = []struct {
+	name string
+	args []string
+}{
+	{"merge", []string{"-intf", "gx,gy"}},
+	{"namespace", []string{"-intf", "gx,gy", "-conflict", "namespace"}},
+	{"avpcode", []string{"-intf", "gx", "-numberFormat", "avpcode"}},
+	{"optional", []string{"-intf", "gx,gy", "-optional"}},
+	{"config", []string{"-config", "testdata/config.yaml"}},
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := t.TempDir()
+			args := append([]string{"-d", "testdata/dict", "-out", out, "-goOut", out, "-goPackage", "example.com/diameterpb"}, tc.args...)
+			if err := run(args, io.Discard); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "golden", tc.name)
+			if *update {
+				if err := os.RemoveAll(golden); err != nil {
+					t.Fatal(err)
+				}
+				if err := copyDir(out, golden); err != nil {
+					t.Fatal(err)
+				}
+			}
+			compareDirs(t, golden, out)
+		})
+	}
+}
+
+// TestDeterministic generates the same files several times, as map iteration
+// order changes between runs.
+func TestDeterministic(t *testing.T) {
+	var first []byte
+	for i := 0; i < 10; i++ {
+		var out bytes.Buffer
+		if err := run([]string{"-d", "testdata/dict", "-intf", "gy,gx", "-conflict", "namespace"}, &out); err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = out.Bytes()
+		} else if !bytes.Equal(first, out.Bytes()) {
+			t.Fatalf("run %d differs from the first run:\n%s\nfirst run:\n%s", i, out.Bytes(), first)
+		}
+	}
+}
+
+func compareDirs(t *testing.T, want, got string) {
+	t.Helper()
+	wantFiles, err := listFiles(want)
+	if err != nil {
+		t.Fatalf("%s (run go test -update to create the golden files)", err)
+	}
+	gotFiles, err := listFiles(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, name := range gotFiles {
+		names[name] = true
+	}
+	for _, name := range wantFiles {
+		if !names[name] {
+			t.Errorf("%s was not generated", name)
+			continue
+		}
+		delete(names, name)
+		wantData, err := os.ReadFile(filepath.Join(want, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotData, err := os.ReadFile(filepath.Join(got, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(wantData, gotData) {
+			t.Errorf("%s differs from %s:\n%s", name, filepath.Join(want, name), gotData)
+		}
+	}
+	for name := range names {
+		t.Errorf("%s was generated but has no golden file", name)
+	}
+}
+
+// listFiles returns the paths of the files below dir, relative to dir.
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, rel)
+		return err
+	})
+	return files, err
+}
+
+func copyDir(src, dst string) error {
+	files, err := listFiles(src)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dst, name)), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dst, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,4 +2,13 @@ module tools
 
 go 1.19
 
-require github.com/fiorix/go-diameter/v4 v4.0.4
+require (
+	github.com/fiorix/go-diameter/v4 v4.0.4
+	google.golang.org/protobuf v1.31.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/ishidawataru/sctp v0.0.0-20190922091402-408ec287e38c // indirect
+	golang.org/x/net v0.0.0-20191007182048-72f939374954 // indirect
+)
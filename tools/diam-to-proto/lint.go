@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"tools/diamproto"
+)
+
+// runLint implements the lint subcommand and returns the process exit
+// status: 1 if any issue was found, 0 otherwise.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	folders := newFolderFlag()
+	flags.Var(folders, "d", "Comma separated list of folders to load")
+	intf := flags.String("intf", "", "Comma separated list of interface aliases, application names or IDs to lint (all if empty)")
+	flags.Parse(args)
+
+	dictionary, err := diamproto.LoadDictionary(folders.elements...)
+	if err != nil {
+		log.Printf("Failed to load dictionaries: %s", err)
+		return 1
+	}
+
+	var selected map[uint32]bool
+	if *intf != "" {
+		selected = make(map[uint32]bool)
+		for _, token := range strings.Split(*intf, ",") {
+			id, _, err := diamproto.ResolveApplication(dictionary.P, token)
+			if err != nil {
+				log.Printf("Invalid interface: %s", err)
+				return 1
+			}
+			selected[id] = true
+		}
+	}
+
+	issues := dictionary.Lint(selected)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		fmt.Printf("%d issues found\n", len(issues))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestLintExitStatus(t *testing.T) {
+	if status := runLint([]string{"-d", "testdata/dict"}); status != 0 {
+		t.Errorf("got exit status %d for the sample dictionaries, want 0", status)
+	}
+	if status := runLint([]string{"-d", "testdata/dict,testdata/lint"}); status != 1 {
+		t.Errorf("got exit status %d for dictionaries with issues, want 1", status)
+	}
+	if status := runLint([]string{"-d", "testdata/dict,testdata/lint", "-intf", "gx"}); status != 0 {
+		t.Errorf("got exit status %d for the Gx application, want 0", status)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+
+	"tools/diamproto"
+)
+
+type AppListing struct {
+	ID       uint32           `json:"id"`
+	Name     string           `json:"name"`
+	Type     string           `json:"type,omitempty"`
+	File     string           `json:"file"`
+	Vendors  []VendorListing  `json:"vendors,omitempty"`
+	Commands []CommandListing `json:"commands,omitempty"`
+	AVPs     []AVPListing     `json:"avps,omitempty"`
+}
+
+type VendorListing struct {
+	ID   uint32 `json:"id"`
+	Name string `json:"name"`
+}
+
+type CommandListing struct {
+	Code    uint32        `json:"code"`
+	Name    string        `json:"name"`
+	Short   string        `json:"short"`
+	Request []RuleListing `json:"request"`
+	Answer  []RuleListing `json:"answer"`
+}
+
+type RuleListing struct {
+	AVP      string `json:"avp"`
+	Required bool   `json:"required"`
+	Min      int    `json:"min"`
+	Max      int    `json:"max"`
+}
+
+type AVPListing struct {
+	Code     uint32        `json:"code"`
+	VendorID uint32        `json:"vendorId"`
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Must     string        `json:"must,omitempty"`
+	Enum     []EnumListing `json:"enum,omitempty"`
+	Rules    []RuleListing `json:"rules,omitempty"`
+}
+
+type EnumListing struct {
+	Code int32  `json:"code"`
+	Name string `json:"name"`
+}
+
+// runList implements the list subcommand, printing the applications,
+// commands and AVPs of the loaded dictionaries to stdout.
+func runList(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	folders := newFolderFlag()
+	flags.Var(folders, "d", "Comma separated list of folders to load")
+	asJSON := flags.Bool("json", false, "Print JSON instead of tables")
+	intf := flags.String("intf", "", "Comma separated list of interface aliases, application names or IDs to list (all if empty)")
+	flags.Parse(args)
+
+	dictionary, err := diamproto.LoadDictionary(folders.elements...)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionaries: %w", err)
+	}
+
+	var selected map[uint32]bool
+	if *intf != "" {
+		selected = make(map[uint32]bool)
+		for _, token := range strings.Split(*intf, ",") {
+			id, _, err := diamproto.ResolveApplication(dictionary.P, token)
+			if err != nil {
+				return fmt.Errorf("invalid interface: %w", err)
+			}
+			selected[id] = true
+		}
+	}
+
+	var listings []AppListing
+	for _, app := range dictionary.P.Apps() {
+		if selected == nil || selected[app.ID] {
+			listings = append(listings, listApp(dictionary, app))
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(listings); err != nil {
+			return fmt.Errorf("failed to encode listing: %w", err)
+		}
+		return nil
+	}
+	printListings(stdout, listings)
+	return nil
+}
+
+func listApp(d *diamproto.Dictionary, app *dict.App) AppListing {
+	listing := AppListing{ID: app.ID, Name: app.Name, Type: app.Type, File: d.AppSource(app)}
+	for _, vendor := range app.Vendor {
+		listing.Vendors = append(listing.Vendors, VendorListing{ID: vendor.ID, Name: vendor.Name})
+	}
+	for _, command := range app.Command {
+		listing.Commands = append(listing.Commands, CommandListing{
+			Code:    command.Code,
+			Name:    command.Name,
+			Short:   command.Short,
+			Request: listRules(command.Request.Rule),
+			Answer:  listRules(command.Answer.Rule),
+		})
+	}
+	for _, avp := range app.AVP {
+		a := AVPListing{
+			Code:     avp.Code,
+			VendorID: avp.VendorID,
+			Name:     avp.Name,
+			Type:     avp.Data.TypeName,
+			Must:     avp.Must,
+			Rules:    listRules(avp.Data.Rule),
+		}
+		for _, enum := range avp.Data.Enum {
+			a.Enum = append(a.Enum, EnumListing{Code: enum.Code, Name: enum.Name})
+		}
+		listing.AVPs = append(listing.AVPs, a)
+	}
+	return listing
+}
+
+func listRules(rules []*dict.Rule) []RuleListing {
+	var listing []RuleListing
+	for _, r := range rules {
+		listing = append(listing, RuleListing{AVP: r.AVP, Required: r.Required, Min: r.Min, Max: r.Max})
+	}
+	return listing
+}
+
+func printListings(out io.Writer, listings []AppListing) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, app := range listings {
+		fmt.Fprintf(w, "Application %d\t%s\t%s\t%s\n", app.ID, app.Name, app.Type, app.File)
+		for _, vendor := range app.Vendors {
+			fmt.Fprintf(w, "  Vendor %d\t%s\n", vendor.ID, vendor.Name)
+		}
+		w.Flush()
+
+		if len(app.Commands) > 0 {
+			fmt.Fprintln(w, "  Commands:")
+			for _, command := range app.Commands {
+				fmt.Fprintf(w, "    %d\t%s (%sR/%sA)\n", command.Code, command.Name, command.Short, command.Short)
+				printRules(w, "Request", command.Request)
+				printRules(w, "Answer", command.Answer)
+			}
+			w.Flush()
+		}
+
+		if len(app.AVPs) > 0 {
+			fmt.Fprintln(w, "  AVPs:\n    CODE\tVENDOR\tNAME\tTYPE\tMUST")
+			for _, avp := range app.AVPs {
+				fmt.Fprintf(w, "    %d\t%d\t%s\t%s\t%s\n", avp.Code, avp.VendorID, avp.Name, avp.Type, avp.Must)
+				for _, enum := range avp.Enum {
+					fmt.Fprintf(w, "    \t\t  %d %s\t\t\n", enum.Code, enum.Name)
+				}
+			}
+			w.Flush()
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+func printRules(w io.Writer, title string, rules []RuleListing) {
+	fmt.Fprintf(w, "      %s:\n", title)
+	for _, r := range rules {
+		fmt.Fprintf(w, "        %s\trequired=%t\tmin=%d\tmax=%d\n", r.AVP, r.Required, r.Min, r.Max)
+	}
+}
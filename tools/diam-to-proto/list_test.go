@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	var out bytes.Buffer
+	if err := runList([]string{"-d", "testdata/dict", "-intf", "gx"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Application 16777238  Gx Charging Control  auth  testdata/dict/gx.xml",
+		"272  Credit-Control (CCR/CCA)",
+		"Framed-IP-Address    required=false  min=0  max=1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("listing lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Application 0") {
+		t.Errorf("listing of gx contains the base application:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runList([]string{"-d", "testdata/dict", "-json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var listings []AppListing
+	if err := json.Unmarshal(out.Bytes(), &listings); err != nil {
+		t.Fatal(err)
+	}
+	if len(listings) != 3 || listings[1].ID != 16777238 || listings[1].Commands[0].Name != "Credit-Control" {
+		t.Errorf("unexpected JSON listing %+v", listings)
+	}
+
+	err := runList([]string{"-d", "testdata/dict", "-intf", "gx,unknown"}, &out)
+	if err == nil || !strings.Contains(err.Error(), `unknown application "unknown"`) {
+		t.Errorf("got error %v, want an unknown application error", err)
+	}
+}
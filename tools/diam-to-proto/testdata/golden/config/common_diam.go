@@ -0,0 +1,199 @@
+// Code generated by diam-to-proto. DO NOT EDIT.
+
+package diameterpb
+
+import (
+	"fmt"
+
+	diameteropts "example.com/diameter/options"
+	"github.com/fiorix/go-diameter/v4/diam"
+	"github.com/fiorix/go-diameter/v4/diam/avp"
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// FromDiameter fills m with the AVPs grouped in a.
+func (m *QoS) FromDiameter(a *diam.AVP) error {
+	g, ok := a.Data.(*diam.GroupedAVP)
+	if !ok {
+		return unexpectedType(a)
+	}
+	return m.fromAVPs(g.AVP)
+}
+
+// ToDiameter builds the grouped AVP data represented by m.
+func (m *QoS) ToDiameter() (*diam.GroupedAVP, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	return &diam.GroupedAVP{AVP: avps}, nil
+}
+
+func (m *QoS) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 515 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			if m.Bandwidth != nil {
+				return conflictingAlternative(a)
+			}
+			m.Bandwidth = &QoS_MaxRequestedBandwidthDL{MaxRequestedBandwidthDL: wrapperspb.UInt32(uint32(v))}
+		case a.Code == 516 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			if m.Bandwidth != nil {
+				return conflictingAlternative(a)
+			}
+			m.Bandwidth = &QoS_MaxRequestedBandwidthUL{MaxRequestedBandwidthUL: wrapperspb.UInt32(uint32(v))}
+		case a.Code == 1028 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			x := uint32(v)
+			m.Qci = &x
+		default:
+			m.UnknownAvps = append(m.UnknownAvps, &diameteropts.RawAvp{Code: a.Code, VendorId: a.VendorID, Flags: uint32(a.Flags), Data: a.Data.Serialize()})
+		}
+	}
+	return nil
+}
+
+func (m *QoS) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	if x, ok := m.Bandwidth.(*QoS_MaxRequestedBandwidthDL); ok && x.MaxRequestedBandwidthDL != nil {
+		avps = append(avps, diam.NewAVP(515, avp.Mbit, 10415, datatype.Unsigned32(x.MaxRequestedBandwidthDL.GetValue())))
+	}
+	if x, ok := m.Bandwidth.(*QoS_MaxRequestedBandwidthUL); ok && x.MaxRequestedBandwidthUL != nil {
+		avps = append(avps, diam.NewAVP(516, avp.Mbit, 10415, datatype.Unsigned32(x.MaxRequestedBandwidthUL.GetValue())))
+	}
+	if m.Qci != nil {
+		avps = append(avps, diam.NewAVP(1028, avp.Mbit, 10415, datatype.Enumerated(*m.Qci)))
+	}
+	for _, x := range m.UnknownAvps {
+		avps = append(avps, diam.NewAVP(x.Code, uint8(x.Flags), x.VendorId, datatype.Unknown(x.Data)))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *QoS) Validate() error {
+	return nil
+}
+
+// FromDiameter fills m with the AVPs grouped in a.
+func (m *SubscriptionId) FromDiameter(a *diam.AVP) error {
+	g, ok := a.Data.(*diam.GroupedAVP)
+	if !ok {
+		return unexpectedType(a)
+	}
+	return m.fromAVPs(g.AVP)
+}
+
+// ToDiameter builds the grouped AVP data represented by m.
+func (m *SubscriptionId) ToDiameter() (*diam.GroupedAVP, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	return &diam.GroupedAVP{AVP: avps}, nil
+}
+
+func (m *SubscriptionId) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 444 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.Data = string(v)
+		case a.Code == 450 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SubscriptionIdType = SubscriptionIdTypeEnum(v)
+		default:
+			m.UnknownAvps = append(m.UnknownAvps, &diameteropts.RawAvp{Code: a.Code, VendorId: a.VendorID, Flags: uint32(a.Flags), Data: a.Data.Serialize()})
+		}
+	}
+	return nil
+}
+
+func (m *SubscriptionId) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(444, avp.Mbit, 0, datatype.UTF8String(m.Data)))
+
+	avps = append(avps, diam.NewAVP(450, avp.Mbit, 0, datatype.Enumerated(m.SubscriptionIdType)))
+
+	for _, x := range m.UnknownAvps {
+		avps = append(avps, diam.NewAVP(x.Code, uint8(x.Flags), x.VendorId, datatype.Unknown(x.Data)))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *SubscriptionId) Validate() error {
+	if m.Data == "" {
+		return fmt.Errorf("missing required AVP Subscription-Id-Data")
+	}
+	return nil
+}
+
+// Dictionary is used to build the Diameter messages returned by ToDiameter.
+var Dictionary = dict.Default
+
+func unexpectedType(a *diam.AVP) error {
+	return fmt.Errorf("AVP %d (vendor %d): unexpected data type %T", a.Code, a.VendorID, a.Data)
+}
+
+func conflictingAlternative(a *diam.AVP) error {
+	return fmt.Errorf("AVP %d (vendor %d): an alternative AVP was already decoded", a.Code, a.VendorID)
+}
+
+// newDiameterHeader returns the header of msg, along with its Session-Id.
+func newDiameterHeader(msg *diam.Message) *diameteropts.DiameterHeader {
+	flags := msg.Header.CommandFlags
+	h := &diameteropts.DiameterHeader{
+		ApplicationId: msg.Header.ApplicationID,
+		CommandCode:   msg.Header.CommandCode,
+		Request:       flags&diam.RequestFlag != 0,
+		Proxiable:     flags&diam.ProxiableFlag != 0,
+		Error:         flags&diam.ErrorFlag != 0,
+		Retransmitted: flags&diam.RetransmittedFlag != 0,
+		HopByHopId:    msg.Header.HopByHopID,
+		EndToEndId:    msg.Header.EndToEndID,
+	}
+	for _, a := range msg.AVP {
+		if v, ok := a.Data.(datatype.UTF8String); ok && a.Code == avp.SessionID && a.VendorID == 0 {
+			h.SessionId = string(v)
+		}
+	}
+	return h
+}
+
+// setDiameterHeader sets the identifiers and the P, E and T flags of msg
+// from h, the other fields following from the message.
+func setDiameterHeader(msg *diam.Message, h *diameteropts.DiameterHeader) {
+	msg.Header.HopByHopID = h.GetHopByHopId()
+	msg.Header.EndToEndID = h.GetEndToEndId()
+	if h.GetProxiable() {
+		msg.Header.CommandFlags |= diam.ProxiableFlag
+	}
+	if h.GetError() {
+		msg.Header.CommandFlags |= diam.ErrorFlag
+	}
+	if h.GetRetransmitted() {
+		msg.Header.CommandFlags |= diam.RetransmittedFlag
+	}
+}
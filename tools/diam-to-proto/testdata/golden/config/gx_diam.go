@@ -0,0 +1,310 @@
+// Code generated by diam-to-proto. DO NOT EDIT.
+
+package diameterpb
+
+import (
+	"fmt"
+	"time"
+
+	diameteropts "example.com/diameter/options"
+	"github.com/fiorix/go-diameter/v4/diam"
+	"github.com/fiorix/go-diameter/v4/diam/avp"
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromDiameter fills m with the AVPs of msg.
+func (m *GxCreditControlRequest) FromDiameter(msg *diam.Message) error {
+	return m.fromAVPs(msg.AVP)
+}
+
+// ToDiameter builds the Diameter message represented by m.
+func (m *GxCreditControlRequest) ToDiameter() (*diam.Message, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	msg := diam.NewMessage(272, diam.RequestFlag, 16777238, 0, 0, Dictionary)
+	for _, a := range avps {
+		msg.AddAVP(a)
+	}
+	return msg, nil
+}
+
+func (m *GxCreditControlRequest) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 8 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.OctetString)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.FramedIPAddress = []byte(v)
+		case a.Code == 55 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Time)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.EventTimestamp = timestamppb.New(time.Time(v))
+		case a.Code == 258 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.AuthApplicationId = uint32(v)
+		case a.Code == 263 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SessionId = string(v)
+		case a.Code == 264 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginHost = []byte(v)
+		case a.Code == 296 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginRealm = []byte(v)
+		case a.Code == 443 && a.VendorID == 0:
+			x := &SubscriptionId{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.SubscriptionId = append(m.SubscriptionId, x)
+		case a.Code == 1016 && a.VendorID == 10415:
+			x := &QoS{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.QoSInformation = x
+		default:
+			m.UnknownAvps = append(m.UnknownAvps, &diameteropts.RawAvp{Code: a.Code, VendorId: a.VendorID, Flags: uint32(a.Flags), Data: a.Data.Serialize()})
+		}
+	}
+	return nil
+}
+
+func (m *GxCreditControlRequest) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	if len(m.FramedIPAddress) > 0 {
+		avps = append(avps, diam.NewAVP(8, avp.Mbit, 0, datatype.OctetString(m.FramedIPAddress)))
+	}
+	if m.EventTimestamp != nil {
+		avps = append(avps, diam.NewAVP(55, avp.Mbit, 0, datatype.Time(m.EventTimestamp.AsTime())))
+	}
+	avps = append(avps, diam.NewAVP(258, avp.Mbit, 0, datatype.Unsigned32(m.AuthApplicationId)))
+
+	avps = append(avps, diam.NewAVP(263, avp.Mbit, 0, datatype.UTF8String(m.SessionId)))
+
+	avps = append(avps, diam.NewAVP(264, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginHost)))
+
+	avps = append(avps, diam.NewAVP(296, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginRealm)))
+
+	for _, x := range m.SubscriptionId {
+		g, err := x.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(443, avp.Mbit, 0, g))
+	}
+	if m.QoSInformation != nil {
+		g, err := m.QoSInformation.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(1016, avp.Mbit, 10415, g))
+	}
+	for _, x := range m.UnknownAvps {
+		avps = append(avps, diam.NewAVP(x.Code, uint8(x.Flags), x.VendorId, datatype.Unknown(x.Data)))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *GxCreditControlRequest) Validate() error {
+	if m.SessionId == "" {
+		return fmt.Errorf("missing required AVP session_id")
+	}
+	if len(m.OriginHost) == 0 {
+		return fmt.Errorf("missing required AVP Origin-Host")
+	}
+	if len(m.OriginRealm) == 0 {
+		return fmt.Errorf("missing required AVP Origin-Realm")
+	}
+	for _, x := range m.SubscriptionId {
+		if err := x.Validate(); err != nil {
+			return fmt.Errorf("Subscription-Id: %w", err)
+		}
+	}
+	if m.QoSInformation != nil {
+		if err := m.QoSInformation.Validate(); err != nil {
+			return fmt.Errorf("QoS-Information: %w", err)
+		}
+	}
+	return nil
+}
+
+// FromDiameter fills m with the AVPs of msg.
+func (m *GxCreditControlAnswer) FromDiameter(msg *diam.Message) error {
+	return m.fromAVPs(msg.AVP)
+}
+
+// ToDiameter builds the Diameter message represented by m.
+func (m *GxCreditControlAnswer) ToDiameter() (*diam.Message, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	msg := diam.NewMessage(272, 0, 16777238, 0, 0, Dictionary)
+	for _, a := range avps {
+		msg.AddAVP(a)
+	}
+	return msg, nil
+}
+
+func (m *GxCreditControlAnswer) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 263 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SessionId = string(v)
+		case a.Code == 264 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginHost = []byte(v)
+		case a.Code == 268 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.ResultCode = uint32(v)
+		case a.Code == 296 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginRealm = []byte(v)
+		case a.Code == 1005 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.OctetString)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.ChargingRuleName = append(m.ChargingRuleName, []byte(v))
+		case a.Code == 1009 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			x := OnlineEnum(v)
+			m.Online = &x
+		case a.Code == 1016 && a.VendorID == 10415:
+			x := &QoS{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.QoSInformation = x
+		default:
+			m.UnknownAvps = append(m.UnknownAvps, &diameteropts.RawAvp{Code: a.Code, VendorId: a.VendorID, Flags: uint32(a.Flags), Data: a.Data.Serialize()})
+		}
+	}
+	return nil
+}
+
+func (m *GxCreditControlAnswer) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(263, avp.Mbit, 0, datatype.UTF8String(m.SessionId)))
+
+	avps = append(avps, diam.NewAVP(264, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginHost)))
+
+	avps = append(avps, diam.NewAVP(268, avp.Mbit, 0, datatype.Unsigned32(m.ResultCode)))
+
+	avps = append(avps, diam.NewAVP(296, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginRealm)))
+
+	for _, x := range m.ChargingRuleName {
+		avps = append(avps, diam.NewAVP(1005, avp.Mbit, 10415, datatype.OctetString(x)))
+	}
+	if m.Online != nil {
+		avps = append(avps, diam.NewAVP(1009, avp.Mbit, 10415, datatype.Enumerated(*m.Online)))
+	}
+	if m.QoSInformation != nil {
+		g, err := m.QoSInformation.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(1016, avp.Mbit, 10415, g))
+	}
+	for _, x := range m.UnknownAvps {
+		avps = append(avps, diam.NewAVP(x.Code, uint8(x.Flags), x.VendorId, datatype.Unknown(x.Data)))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *GxCreditControlAnswer) Validate() error {
+	if m.SessionId == "" {
+		return fmt.Errorf("missing required AVP session_id")
+	}
+	if len(m.OriginHost) == 0 {
+		return fmt.Errorf("missing required AVP Origin-Host")
+	}
+	if len(m.OriginRealm) == 0 {
+		return fmt.Errorf("missing required AVP Origin-Realm")
+	}
+	if m.QoSInformation != nil {
+		if err := m.QoSInformation.Validate(); err != nil {
+			return fmt.Errorf("QoS-Information: %w", err)
+		}
+	}
+	return nil
+}
+
+// FromDiameter fills m with the header of msg, and with the request or the
+// answer holding its AVPs depending on the R flag.
+func (m *GxCreditControlEnvelope) FromDiameter(msg *diam.Message) error {
+	m.Header = newDiameterHeader(msg)
+	if m.Header.Request {
+		body := &GxCreditControlRequest{}
+		if err := body.FromDiameter(msg); err != nil {
+			return err
+		}
+		m.Body = &GxCreditControlEnvelope_Request{Request: body}
+		return nil
+	}
+	body := &GxCreditControlAnswer{}
+	if err := body.FromDiameter(msg); err != nil {
+		return err
+	}
+	m.Body = &GxCreditControlEnvelope_Answer{Answer: body}
+	return nil
+}
+
+// ToDiameter builds the Diameter message of the body of m, with the
+// identifiers and flags of its header.
+func (m *GxCreditControlEnvelope) ToDiameter() (*diam.Message, error) {
+	var msg *diam.Message
+	var err error
+	if request := m.GetRequest(); request != nil {
+		msg, err = request.ToDiameter()
+	} else if answer := m.GetAnswer(); answer != nil {
+		msg, err = answer.ToDiameter()
+	} else {
+		return nil, fmt.Errorf("GxCreditControlEnvelope: missing body")
+	}
+	if err != nil {
+		return nil, err
+	}
+	setDiameterHeader(msg, m.Header)
+	return msg, nil
+}
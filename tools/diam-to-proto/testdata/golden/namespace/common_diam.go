@@ -0,0 +1,318 @@
+// Code generated by diam-to-proto. DO NOT EDIT.
+
+package diameterpb
+
+import (
+	"fmt"
+
+	"github.com/fiorix/go-diameter/v4/diam"
+	"github.com/fiorix/go-diameter/v4/diam/avp"
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// FromDiameter fills m with the AVPs grouped in a.
+func (m *GxQoSInformation) FromDiameter(a *diam.AVP) error {
+	g, ok := a.Data.(*diam.GroupedAVP)
+	if !ok {
+		return unexpectedType(a)
+	}
+	return m.fromAVPs(g.AVP)
+}
+
+// ToDiameter builds the grouped AVP data represented by m.
+func (m *GxQoSInformation) ToDiameter() (*diam.GroupedAVP, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	return &diam.GroupedAVP{AVP: avps}, nil
+}
+
+func (m *GxQoSInformation) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 1028 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			x := QoSClassIdentifierEnum(v)
+			m.QoSClassIdentifier = &x
+		case a.Code == 516 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.MaxRequestedBandwidthUL = wrapperspb.UInt32(uint32(v))
+		case a.Code == 515 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.MaxRequestedBandwidthDL = wrapperspb.UInt32(uint32(v))
+		}
+	}
+	return nil
+}
+
+func (m *GxQoSInformation) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	if m.QoSClassIdentifier != nil {
+		avps = append(avps, diam.NewAVP(1028, avp.Mbit, 10415, datatype.Enumerated(*m.QoSClassIdentifier)))
+	}
+	if m.MaxRequestedBandwidthUL != nil {
+		avps = append(avps, diam.NewAVP(516, avp.Mbit, 10415, datatype.Unsigned32(m.MaxRequestedBandwidthUL.GetValue())))
+	}
+	if m.MaxRequestedBandwidthDL != nil {
+		avps = append(avps, diam.NewAVP(515, avp.Mbit, 10415, datatype.Unsigned32(m.MaxRequestedBandwidthDL.GetValue())))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *GxQoSInformation) Validate() error {
+	return nil
+}
+
+// FromDiameter fills m with the AVPs grouped in a.
+func (m *UsedServiceUnit) FromDiameter(a *diam.AVP) error {
+	g, ok := a.Data.(*diam.GroupedAVP)
+	if !ok {
+		return unexpectedType(a)
+	}
+	return m.fromAVPs(g.AVP)
+}
+
+// ToDiameter builds the grouped AVP data represented by m.
+func (m *UsedServiceUnit) ToDiameter() (*diam.GroupedAVP, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	return &diam.GroupedAVP{AVP: avps}, nil
+}
+
+func (m *UsedServiceUnit) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 420 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.CCTime = wrapperspb.UInt32(uint32(v))
+		case a.Code == 421 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned64)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.CCTotalOctets = wrapperspb.UInt64(uint64(v))
+		case a.Code == 872 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.ReportingReason = append(m.ReportingReason, ReportingReasonEnum(v))
+		}
+	}
+	return nil
+}
+
+func (m *UsedServiceUnit) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	if m.CCTime != nil {
+		avps = append(avps, diam.NewAVP(420, avp.Mbit, 0, datatype.Unsigned32(m.CCTime.GetValue())))
+	}
+	if m.CCTotalOctets != nil {
+		avps = append(avps, diam.NewAVP(421, avp.Mbit, 0, datatype.Unsigned64(m.CCTotalOctets.GetValue())))
+	}
+	for _, x := range m.ReportingReason {
+		avps = append(avps, diam.NewAVP(872, avp.Mbit, 10415, datatype.Enumerated(x)))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *UsedServiceUnit) Validate() error {
+	return nil
+}
+
+// FromDiameter fills m with the AVPs grouped in a.
+func (m *GrantedServiceUnit) FromDiameter(a *diam.AVP) error {
+	g, ok := a.Data.(*diam.GroupedAVP)
+	if !ok {
+		return unexpectedType(a)
+	}
+	return m.fromAVPs(g.AVP)
+}
+
+// ToDiameter builds the grouped AVP data represented by m.
+func (m *GrantedServiceUnit) ToDiameter() (*diam.GroupedAVP, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	return &diam.GroupedAVP{AVP: avps}, nil
+}
+
+func (m *GrantedServiceUnit) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 420 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.CCTime = wrapperspb.UInt32(uint32(v))
+		case a.Code == 421 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned64)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.CCTotalOctets = wrapperspb.UInt64(uint64(v))
+		}
+	}
+	return nil
+}
+
+func (m *GrantedServiceUnit) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	if m.CCTime != nil {
+		avps = append(avps, diam.NewAVP(420, avp.Mbit, 0, datatype.Unsigned32(m.CCTime.GetValue())))
+	}
+	if m.CCTotalOctets != nil {
+		avps = append(avps, diam.NewAVP(421, avp.Mbit, 0, datatype.Unsigned64(m.CCTotalOctets.GetValue())))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *GrantedServiceUnit) Validate() error {
+	return nil
+}
+
+// FromDiameter fills m with the AVPs grouped in a.
+func (m *GyQoSInformation) FromDiameter(a *diam.AVP) error {
+	g, ok := a.Data.(*diam.GroupedAVP)
+	if !ok {
+		return unexpectedType(a)
+	}
+	return m.fromAVPs(g.AVP)
+}
+
+// ToDiameter builds the grouped AVP data represented by m.
+func (m *GyQoSInformation) ToDiameter() (*diam.GroupedAVP, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	return &diam.GroupedAVP{AVP: avps}, nil
+}
+
+func (m *GyQoSInformation) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 1028 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.QoSClassIdentifier = QoSClassIdentifierEnum(v)
+		case a.Code == 516 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.MaxRequestedBandwidthUL = wrapperspb.UInt32(uint32(v))
+		}
+	}
+	return nil
+}
+
+func (m *GyQoSInformation) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(1028, avp.Mbit, 10415, datatype.Enumerated(m.QoSClassIdentifier)))
+
+	if m.MaxRequestedBandwidthUL != nil {
+		avps = append(avps, diam.NewAVP(516, avp.Mbit, 10415, datatype.Unsigned32(m.MaxRequestedBandwidthUL.GetValue())))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *GyQoSInformation) Validate() error {
+	return nil
+}
+
+// FromDiameter fills m with the AVPs grouped in a.
+func (m *SubscriptionId) FromDiameter(a *diam.AVP) error {
+	g, ok := a.Data.(*diam.GroupedAVP)
+	if !ok {
+		return unexpectedType(a)
+	}
+	return m.fromAVPs(g.AVP)
+}
+
+// ToDiameter builds the grouped AVP data represented by m.
+func (m *SubscriptionId) ToDiameter() (*diam.GroupedAVP, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	return &diam.GroupedAVP{AVP: avps}, nil
+}
+
+func (m *SubscriptionId) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 450 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SubscriptionIdType = SubscriptionIdTypeEnum(v)
+		case a.Code == 444 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SubscriptionIdData = string(v)
+		}
+	}
+	return nil
+}
+
+func (m *SubscriptionId) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(450, avp.Mbit, 0, datatype.Enumerated(m.SubscriptionIdType)))
+
+	avps = append(avps, diam.NewAVP(444, avp.Mbit, 0, datatype.UTF8String(m.SubscriptionIdData)))
+
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *SubscriptionId) Validate() error {
+	if m.SubscriptionIdData == "" {
+		return fmt.Errorf("missing required AVP Subscription-Id-Data")
+	}
+	return nil
+}
+
+// Dictionary is used to build the Diameter messages returned by ToDiameter.
+var Dictionary = dict.Default
+
+func unexpectedType(a *diam.AVP) error {
+	return fmt.Errorf("AVP %d (vendor %d): unexpected data type %T", a.Code, a.VendorID, a.Data)
+}
+
+func conflictingAlternative(a *diam.AVP) error {
+	return fmt.Errorf("AVP %d (vendor %d): an alternative AVP was already decoded", a.Code, a.VendorID)
+}
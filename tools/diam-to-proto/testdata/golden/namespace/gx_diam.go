@@ -0,0 +1,260 @@
+// Code generated by diam-to-proto. DO NOT EDIT.
+
+package diameterpb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fiorix/go-diameter/v4/diam"
+	"github.com/fiorix/go-diameter/v4/diam/avp"
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FromDiameter fills m with the AVPs of msg.
+func (m *GxChargingControlCreditControlRequestPB) FromDiameter(msg *diam.Message) error {
+	return m.fromAVPs(msg.AVP)
+}
+
+// ToDiameter builds the Diameter message represented by m.
+func (m *GxChargingControlCreditControlRequestPB) ToDiameter() (*diam.Message, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	msg := diam.NewMessage(272, diam.RequestFlag, 16777238, 0, 0, Dictionary)
+	for _, a := range avps {
+		msg.AddAVP(a)
+	}
+	return msg, nil
+}
+
+func (m *GxChargingControlCreditControlRequestPB) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 263 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SessionId = string(v)
+		case a.Code == 264 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginHost = string(v)
+		case a.Code == 296 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginRealm = string(v)
+		case a.Code == 258 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.AuthApplicationId = uint32(v)
+		case a.Code == 443 && a.VendorID == 0:
+			x := &SubscriptionId{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.SubscriptionId = append(m.SubscriptionId, x)
+		case a.Code == 8 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.OctetString)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.FramedIPAddress = []byte(v)
+		case a.Code == 1016 && a.VendorID == 10415:
+			x := &GxQoSInformation{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.QoSInformation = x
+		case a.Code == 55 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Time)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.EventTimestamp = timestamppb.New(time.Time(v))
+		}
+	}
+	return nil
+}
+
+func (m *GxChargingControlCreditControlRequestPB) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(263, avp.Mbit, 0, datatype.UTF8String(m.SessionId)))
+
+	avps = append(avps, diam.NewAVP(264, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginHost)))
+
+	avps = append(avps, diam.NewAVP(296, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginRealm)))
+
+	avps = append(avps, diam.NewAVP(258, avp.Mbit, 0, datatype.Unsigned32(m.AuthApplicationId)))
+
+	for _, x := range m.SubscriptionId {
+		g, err := x.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(443, avp.Mbit, 0, g))
+	}
+	if len(m.FramedIPAddress) > 0 {
+		avps = append(avps, diam.NewAVP(8, avp.Mbit, 0, datatype.OctetString(m.FramedIPAddress)))
+	}
+	if m.QoSInformation != nil {
+		g, err := m.QoSInformation.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(1016, avp.Mbit, 10415, g))
+	}
+	if m.EventTimestamp != nil {
+		avps = append(avps, diam.NewAVP(55, avp.Mbit, 0, datatype.Time(m.EventTimestamp.AsTime())))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *GxChargingControlCreditControlRequestPB) Validate() error {
+	if m.SessionId == "" {
+		return fmt.Errorf("missing required AVP Session-Id")
+	}
+	if m.OriginHost == "" {
+		return fmt.Errorf("missing required AVP Origin-Host")
+	}
+	if m.OriginRealm == "" {
+		return fmt.Errorf("missing required AVP Origin-Realm")
+	}
+	for _, x := range m.SubscriptionId {
+		if err := x.Validate(); err != nil {
+			return fmt.Errorf("Subscription-Id: %w", err)
+		}
+	}
+	if m.QoSInformation != nil {
+		if err := m.QoSInformation.Validate(); err != nil {
+			return fmt.Errorf("QoS-Information: %w", err)
+		}
+	}
+	return nil
+}
+
+// FromDiameter fills m with the AVPs of msg.
+func (m *GxChargingControlCreditControlAnswerPB) FromDiameter(msg *diam.Message) error {
+	return m.fromAVPs(msg.AVP)
+}
+
+// ToDiameter builds the Diameter message represented by m.
+func (m *GxChargingControlCreditControlAnswerPB) ToDiameter() (*diam.Message, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	msg := diam.NewMessage(272, 0, 16777238, 0, 0, Dictionary)
+	for _, a := range avps {
+		msg.AddAVP(a)
+	}
+	return msg, nil
+}
+
+func (m *GxChargingControlCreditControlAnswerPB) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 263 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SessionId = string(v)
+		case a.Code == 268 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.ResultCode = uint32(v)
+		case a.Code == 264 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginHost = string(v)
+		case a.Code == 296 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginRealm = string(v)
+		case a.Code == 1005 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.OctetString)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.ChargingRuleName = append(m.ChargingRuleName, []byte(v))
+		case a.Code == 1009 && a.VendorID == 10415:
+			v, ok := a.Data.(datatype.Enumerated)
+			if !ok {
+				return unexpectedType(a)
+			}
+			x := OnlineEnum(v)
+			m.Online = &x
+		case a.Code == 1016 && a.VendorID == 10415:
+			x := &GxQoSInformation{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.QoSInformation = x
+		}
+	}
+	return nil
+}
+
+func (m *GxChargingControlCreditControlAnswerPB) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(263, avp.Mbit, 0, datatype.UTF8String(m.SessionId)))
+
+	avps = append(avps, diam.NewAVP(268, avp.Mbit, 0, datatype.Unsigned32(m.ResultCode)))
+
+	avps = append(avps, diam.NewAVP(264, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginHost)))
+
+	avps = append(avps, diam.NewAVP(296, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginRealm)))
+
+	for _, x := range m.ChargingRuleName {
+		avps = append(avps, diam.NewAVP(1005, avp.Mbit, 10415, datatype.OctetString(x)))
+	}
+	if m.Online != nil {
+		avps = append(avps, diam.NewAVP(1009, avp.Mbit, 10415, datatype.Enumerated(*m.Online)))
+	}
+	if m.QoSInformation != nil {
+		g, err := m.QoSInformation.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(1016, avp.Mbit, 10415, g))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *GxChargingControlCreditControlAnswerPB) Validate() error {
+	if m.SessionId == "" {
+		return fmt.Errorf("missing required AVP Session-Id")
+	}
+	if m.OriginHost == "" {
+		return fmt.Errorf("missing required AVP Origin-Host")
+	}
+	if m.OriginRealm == "" {
+		return fmt.Errorf("missing required AVP Origin-Realm")
+	}
+	if m.QoSInformation != nil {
+		if err := m.QoSInformation.Validate(); err != nil {
+			return fmt.Errorf("QoS-Information: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+// Code generated by diam-to-proto. DO NOT EDIT.
+
+package diameterpb
+
+import (
+	"fmt"
+
+	"github.com/fiorix/go-diameter/v4/diam"
+	"github.com/fiorix/go-diameter/v4/diam/avp"
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+)
+
+// FromDiameter fills m with the AVPs of msg.
+func (m *ChargingControlCreditControlRequestPB) FromDiameter(msg *diam.Message) error {
+	return m.fromAVPs(msg.AVP)
+}
+
+// ToDiameter builds the Diameter message represented by m.
+func (m *ChargingControlCreditControlRequestPB) ToDiameter() (*diam.Message, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	msg := diam.NewMessage(272, diam.RequestFlag, 4, 0, 0, Dictionary)
+	for _, a := range avps {
+		msg.AddAVP(a)
+	}
+	return msg, nil
+}
+
+func (m *ChargingControlCreditControlRequestPB) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 263 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SessionId = string(v)
+		case a.Code == 264 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginHost = string(v)
+		case a.Code == 296 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginRealm = string(v)
+		case a.Code == 258 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.AuthApplicationId = uint32(v)
+		case a.Code == 443 && a.VendorID == 0:
+			x := &SubscriptionId{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.SubscriptionId = append(m.SubscriptionId, x)
+		case a.Code == 446 && a.VendorID == 0:
+			x := &UsedServiceUnit{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.UsedServiceUnit = append(m.UsedServiceUnit, x)
+		case a.Code == 1016 && a.VendorID == 10415:
+			x := &GyQoSInformation{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.QoSInformation = x
+		}
+	}
+	return nil
+}
+
+func (m *ChargingControlCreditControlRequestPB) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(263, avp.Mbit, 0, datatype.UTF8String(m.SessionId)))
+
+	avps = append(avps, diam.NewAVP(264, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginHost)))
+
+	avps = append(avps, diam.NewAVP(296, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginRealm)))
+
+	avps = append(avps, diam.NewAVP(258, avp.Mbit, 0, datatype.Unsigned32(m.AuthApplicationId)))
+
+	for _, x := range m.SubscriptionId {
+		g, err := x.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(443, avp.Mbit, 0, g))
+	}
+	for _, x := range m.UsedServiceUnit {
+		g, err := x.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(446, avp.Mbit, 0, g))
+	}
+	if m.QoSInformation != nil {
+		g, err := m.QoSInformation.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(1016, avp.Mbit, 10415, g))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *ChargingControlCreditControlRequestPB) Validate() error {
+	if m.SessionId == "" {
+		return fmt.Errorf("missing required AVP Session-Id")
+	}
+	if m.OriginHost == "" {
+		return fmt.Errorf("missing required AVP Origin-Host")
+	}
+	if m.OriginRealm == "" {
+		return fmt.Errorf("missing required AVP Origin-Realm")
+	}
+	for _, x := range m.SubscriptionId {
+		if err := x.Validate(); err != nil {
+			return fmt.Errorf("Subscription-Id: %w", err)
+		}
+	}
+	for _, x := range m.UsedServiceUnit {
+		if err := x.Validate(); err != nil {
+			return fmt.Errorf("Used-Service-Unit: %w", err)
+		}
+	}
+	if m.QoSInformation != nil {
+		if err := m.QoSInformation.Validate(); err != nil {
+			return fmt.Errorf("QoS-Information: %w", err)
+		}
+	}
+	return nil
+}
+
+// FromDiameter fills m with the AVPs of msg.
+func (m *ChargingControlCreditControlAnswerPB) FromDiameter(msg *diam.Message) error {
+	return m.fromAVPs(msg.AVP)
+}
+
+// ToDiameter builds the Diameter message represented by m.
+func (m *ChargingControlCreditControlAnswerPB) ToDiameter() (*diam.Message, error) {
+	avps, err := m.toAVPs()
+	if err != nil {
+		return nil, err
+	}
+	msg := diam.NewMessage(272, 0, 4, 0, 0, Dictionary)
+	for _, a := range avps {
+		msg.AddAVP(a)
+	}
+	return msg, nil
+}
+
+func (m *ChargingControlCreditControlAnswerPB) fromAVPs(avps []*diam.AVP) error {
+	for _, a := range avps {
+		switch {
+		case a.Code == 263 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.UTF8String)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.SessionId = string(v)
+		case a.Code == 268 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.Unsigned32)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.ResultCode = uint32(v)
+		case a.Code == 264 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginHost = string(v)
+		case a.Code == 296 && a.VendorID == 0:
+			v, ok := a.Data.(datatype.DiameterIdentity)
+			if !ok {
+				return unexpectedType(a)
+			}
+			m.OriginRealm = string(v)
+		case a.Code == 431 && a.VendorID == 0:
+			x := &GrantedServiceUnit{}
+			if err := x.FromDiameter(a); err != nil {
+				return err
+			}
+			m.GrantedServiceUnit = x
+		}
+	}
+	return nil
+}
+
+func (m *ChargingControlCreditControlAnswerPB) toAVPs() ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	avps = append(avps, diam.NewAVP(263, avp.Mbit, 0, datatype.UTF8String(m.SessionId)))
+
+	avps = append(avps, diam.NewAVP(268, avp.Mbit, 0, datatype.Unsigned32(m.ResultCode)))
+
+	avps = append(avps, diam.NewAVP(264, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginHost)))
+
+	avps = append(avps, diam.NewAVP(296, avp.Mbit, 0, datatype.DiameterIdentity(m.OriginRealm)))
+
+	if m.GrantedServiceUnit != nil {
+		g, err := m.GrantedServiceUnit.ToDiameter()
+		if err != nil {
+			return nil, err
+		}
+		avps = append(avps, diam.NewAVP(431, avp.Mbit, 0, g))
+	}
+	return avps, nil
+}
+
+// Validate checks that the AVPs of m, and of the grouped AVPs it holds,
+// occur as many times as the dictionary rules allow.
+func (m *ChargingControlCreditControlAnswerPB) Validate() error {
+	if m.SessionId == "" {
+		return fmt.Errorf("missing required AVP Session-Id")
+	}
+	if m.OriginHost == "" {
+		return fmt.Errorf("missing required AVP Origin-Host")
+	}
+	if m.OriginRealm == "" {
+		return fmt.Errorf("missing required AVP Origin-Realm")
+	}
+	if m.GrantedServiceUnit != nil {
+		if err := m.GrantedServiceUnit.Validate(); err != nil {
+			return fmt.Errorf("Granted-Service-Unit: %w", err)
+		}
+	}
+	return nil
+}
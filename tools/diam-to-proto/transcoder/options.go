@@ -0,0 +1,54 @@
+package transcoder
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// options holds the Diameter options set on a descriptor, by field number.
+type options struct {
+	varints map[protowire.Number]uint64
+	bytes   map[protowire.Number][]byte
+}
+
+// readOptions reads the Diameter options from the wire format of descriptor
+// options. This works whether the generated extensions are linked into the
+// binary, or only known as unknown fields, e.g. for dynamic messages.
+func readOptions(opts protoreflect.ProtoMessage) options {
+	o := options{varints: make(map[protowire.Number]uint64), bytes: make(map[protowire.Number][]byte)}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(opts)
+	if err != nil {
+		return o
+	}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return o
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return o
+			}
+			o.varints[num] = v
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return o
+			}
+			o.bytes[num] = v
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return o
+			}
+			b = b[n:]
+		}
+	}
+	return o
+}
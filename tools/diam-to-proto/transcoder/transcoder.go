@@ -0,0 +1,613 @@
+// Package transcoder converts go-diameter messages to and from any protobuf
+// message generated by diam-to-proto, using protobuf reflection and the
+// Diameter metadata of the diameter/options.proto annotations. Supporting a
+// new interface only requires regenerating the protos.
+package transcoder
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fiorix/go-diameter/v4/diam"
+	"github.com/fiorix/go-diameter/v4/diam/avp"
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Field numbers of the extensions declared by diameter/options.proto.
+const (
+	AvpCodeOption       = 51001
+	VendorIdOption      = 51002
+	MandatoryOption     = 51003
+	ProtectedOption     = 51004
+	AvpTypeOption       = 51005
+	RequiredOption      = 51006
+	MinOption           = 51007
+	MaxOption           = 51008
+	ApplicationIdOption = 51101
+	CommandCodeOption   = 51102
+	IsRequestOption     = 51103
+)
+
+const (
+	timestampName = "google.protobuf.Timestamp"
+	wrappersFile  = "google/protobuf/wrappers.proto"
+	// message of the repeated field keeping the AVPs without a field, so
+	// that they are encoded again
+	rawAvpName = "diameter.RawAvp"
+	// message of the header field of envelopes
+	headerName = "diameter.DiameterHeader"
+)
+
+// Decode fills m with the AVPs of msg. If m is an envelope, its header is
+// filled from the header of msg, and its request or answer from the AVPs
+// depending on the R flag.
+func Decode(msg *diam.Message, m proto.Message) error {
+	if info := messageInfoFor(m.ProtoReflect().Descriptor()); info.header != nil {
+		return decodeEnvelope(msg, m.ProtoReflect(), info)
+	}
+	return DecodeAVPs(msg.AVP, m.ProtoReflect())
+}
+
+// Encode builds the Diameter message represented by m, whose message options
+// must carry the application ID and command code. If m is an envelope, the
+// message of its body is built with the identifiers and the P, E and T flags
+// of its header. The dictionary is used by go-diameter to serialize the
+// message.
+func Encode(m proto.Message, dictionary *dict.Parser) (*diam.Message, error) {
+	md := m.ProtoReflect().Descriptor()
+	info := messageInfoFor(md)
+	if info.header != nil {
+		return encodeEnvelope(m.ProtoReflect(), info, dictionary)
+	}
+	if !info.command {
+		return nil, fmt.Errorf("%s is not annotated as a Diameter command", md.FullName())
+	}
+	avps, err := EncodeAVPs(m.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	var flags uint8
+	if info.request {
+		flags = diam.RequestFlag
+	}
+	msg := diam.NewMessage(info.commandCode, flags, info.appId, 0, 0, dictionary)
+	for _, a := range avps {
+		msg.AddAVP(a)
+	}
+	return msg, nil
+}
+
+func decodeEnvelope(msg *diam.Message, m protoreflect.Message, info *messageInfo) error {
+	body := info.answerBody
+	if msg.Header.CommandFlags&diam.RequestFlag != 0 {
+		body = info.requestBody
+	}
+	if body == nil {
+		return fmt.Errorf("%s: no body for command flags %#x", m.Descriptor().FullName(), msg.Header.CommandFlags)
+	}
+	header := m.Mutable(info.header).Message()
+	fields := header.Descriptor().Fields()
+	flags := msg.Header.CommandFlags
+	for name, v := range map[protoreflect.Name]protoreflect.Value{
+		"application_id": protoreflect.ValueOfUint32(msg.Header.ApplicationID),
+		"command_code":   protoreflect.ValueOfUint32(msg.Header.CommandCode),
+		"request":        protoreflect.ValueOfBool(flags&diam.RequestFlag != 0),
+		"proxiable":      protoreflect.ValueOfBool(flags&diam.ProxiableFlag != 0),
+		"error":          protoreflect.ValueOfBool(flags&diam.ErrorFlag != 0),
+		"retransmitted":  protoreflect.ValueOfBool(flags&diam.RetransmittedFlag != 0),
+		"hop_by_hop_id":  protoreflect.ValueOfUint32(msg.Header.HopByHopID),
+		"end_to_end_id":  protoreflect.ValueOfUint32(msg.Header.EndToEndID),
+	} {
+		header.Set(fields.ByName(name), v)
+	}
+	for _, a := range msg.AVP {
+		if s, ok := stringOf(a.Data); ok && a.Code == avp.SessionID && a.VendorID == 0 {
+			header.Set(fields.ByName("session_id"), protoreflect.ValueOfString(s))
+		}
+	}
+	return DecodeAVPs(msg.AVP, m.Mutable(body).Message())
+}
+
+func encodeEnvelope(m protoreflect.Message, info *messageInfo, dictionary *dict.Parser) (*diam.Message, error) {
+	var body protoreflect.Message
+	switch {
+	case info.requestBody != nil && m.Has(info.requestBody):
+		body = m.Get(info.requestBody).Message()
+	case info.answerBody != nil && m.Has(info.answerBody):
+		body = m.Get(info.answerBody).Message()
+	default:
+		return nil, fmt.Errorf("%s: missing body", m.Descriptor().FullName())
+	}
+	msg, err := Encode(body.Interface(), dictionary)
+	if err != nil {
+		return nil, err
+	}
+	header := m.Get(info.header).Message()
+	fields := header.Descriptor().Fields()
+	msg.Header.HopByHopID = uint32(header.Get(fields.ByName("hop_by_hop_id")).Uint())
+	msg.Header.EndToEndID = uint32(header.Get(fields.ByName("end_to_end_id")).Uint())
+	for name, flag := range map[protoreflect.Name]uint8{
+		"proxiable":     diam.ProxiableFlag,
+		"error":         diam.ErrorFlag,
+		"retransmitted": diam.RetransmittedFlag,
+	} {
+		if header.Get(fields.ByName(name)).Bool() {
+			msg.Header.CommandFlags |= flag
+		}
+	}
+	return msg, nil
+}
+
+// DecodeAVPs sets the fields of m from the AVPs mapped to them. AVPs without
+// a field are kept in the repeated diameter.RawAvp field of m if any, and
+// ignored otherwise.
+func DecodeAVPs(avps []*diam.AVP, m protoreflect.Message) error {
+	info := messageInfoFor(m.Descriptor())
+	for _, a := range avps {
+		f, ok := info.byAVP[avpKey{a.Code, a.VendorID}]
+		if !ok {
+			if info.unknown != nil {
+				list := m.Mutable(info.unknown).List()
+				list.Append(rawAvpValue(a, list.NewElement()))
+			}
+			continue
+		}
+		if err := decodeField(a, m, f); err != nil {
+			return fmt.Errorf("%s: %w", f.desc.FullName(), err)
+		}
+	}
+	return nil
+}
+
+// EncodeAVPs returns the AVPs of the fields of m, in field order. Singular
+// fields are encoded when set, or always when annotated as required.
+func EncodeAVPs(m protoreflect.Message) ([]*diam.AVP, error) {
+	var avps []*diam.AVP
+	for _, f := range messageInfoFor(m.Descriptor()).fields {
+		fd := f.desc
+		if fd.IsList() {
+			list := m.Get(fd).List()
+			for i := 0; i < list.Len(); i++ {
+				a, err := encodeValue(list.Get(i), f)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", fd.FullName(), err)
+				}
+				avps = append(avps, a)
+			}
+			continue
+		}
+		if !m.Has(fd) && (!f.required || fd.Message() != nil) {
+			continue
+		}
+		a, err := encodeValue(m.Get(fd), f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fd.FullName(), err)
+		}
+		avps = append(avps, a)
+	}
+	if fd := messageInfoFor(m.Descriptor()).unknown; fd != nil {
+		list := m.Get(fd).List()
+		for i := 0; i < list.Len(); i++ {
+			avps = append(avps, rawAvp(list.Get(i).Message()))
+		}
+	}
+	return avps, nil
+}
+
+// rawAvpValue fills raw, a diameter.RawAvp message, with the header and the
+// serialized data of a.
+func rawAvpValue(a *diam.AVP, raw protoreflect.Value) protoreflect.Value {
+	msg := raw.Message()
+	fields := msg.Descriptor().Fields()
+	msg.Set(fields.ByName("code"), protoreflect.ValueOfUint32(a.Code))
+	msg.Set(fields.ByName("vendor_id"), protoreflect.ValueOfUint32(a.VendorID))
+	msg.Set(fields.ByName("flags"), protoreflect.ValueOfUint32(uint32(a.Flags)))
+	msg.Set(fields.ByName("data"), protoreflect.ValueOfBytes(a.Data.Serialize()))
+	return raw
+}
+
+// rawAvp builds the AVP kept by a diameter.RawAvp message.
+func rawAvp(msg protoreflect.Message) *diam.AVP {
+	fields := msg.Descriptor().Fields()
+	return diam.NewAVP(uint32(msg.Get(fields.ByName("code")).Uint()), uint8(msg.Get(fields.ByName("flags")).Uint()),
+		uint32(msg.Get(fields.ByName("vendor_id")).Uint()), datatype.Unknown(msg.Get(fields.ByName("data")).Bytes()))
+}
+
+type avpKey struct {
+	code, vendorId uint32
+}
+
+type fieldInfo struct {
+	desc     protoreflect.FieldDescriptor
+	code     uint32
+	vendorId uint32
+	flags    uint8
+	avpType  datatype.TypeID
+	required bool
+}
+
+type messageInfo struct {
+	fields []*fieldInfo
+	byAVP  map[avpKey]*fieldInfo
+	// repeated diameter.RawAvp field, if any
+	unknown     protoreflect.FieldDescriptor
+	command     bool
+	appId       uint32
+	commandCode uint32
+	request     bool
+
+	// header and body fields of an envelope
+	header, requestBody, answerBody protoreflect.FieldDescriptor
+}
+
+var messageInfos sync.Map // protoreflect.MessageDescriptor -> *messageInfo
+
+// messageInfoFor reads, once per message type, the options of a message and
+// of its fields. Fields without an AVP code are ignored, but for the one
+// keeping unknown AVPs and those of envelopes.
+func messageInfoFor(md protoreflect.MessageDescriptor) *messageInfo {
+	if info, ok := messageInfos.Load(md); ok {
+		return info.(*messageInfo)
+	}
+	info := &messageInfo{byAVP: make(map[avpKey]*fieldInfo)}
+	opts := readOptions(md.Options())
+	if v, ok := opts.varints[CommandCodeOption]; ok {
+		info.command = true
+		info.commandCode = uint32(v)
+		info.appId = uint32(opts.varints[ApplicationIdOption])
+		info.request = opts.varints[IsRequestOption] != 0
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		opts := readOptions(fd.Options())
+		code, ok := opts.varints[AvpCodeOption]
+		if !ok {
+			if md := fd.Message(); md != nil {
+				info.link(fd, md)
+			}
+			continue
+		}
+		f := &fieldInfo{
+			desc:     fd,
+			code:     uint32(code),
+			vendorId: uint32(opts.varints[VendorIdOption]),
+			required: opts.varints[RequiredOption] != 0,
+		}
+		if opts.varints[MandatoryOption] != 0 {
+			f.flags |= avp.Mbit
+		}
+		if opts.varints[ProtectedOption] != 0 {
+			f.flags |= avp.Pbit
+		}
+		f.avpType = datatype.UnknownType
+		if id, ok := datatype.Available[string(opts.bytes[AvpTypeOption])]; ok {
+			f.avpType = id
+		}
+		info.fields = append(info.fields, f)
+		info.byAVP[avpKey{f.code, f.vendorId}] = f
+	}
+	actual, _ := messageInfos.LoadOrStore(md, info)
+	return actual.(*messageInfo)
+}
+
+// link records a message field without an AVP code that is known to the
+// transcoder: unknown AVPs, or the header and body of an envelope.
+func (info *messageInfo) link(fd protoreflect.FieldDescriptor, md protoreflect.MessageDescriptor) {
+	switch {
+	case fd.IsList():
+		if md.FullName() == rawAvpName {
+			info.unknown = fd
+		}
+	case md.FullName() == headerName:
+		info.header = fd
+	default:
+		opts := readOptions(md.Options())
+		if _, ok := opts.varints[CommandCodeOption]; !ok {
+			return
+		}
+		if opts.varints[IsRequestOption] != 0 {
+			info.requestBody = fd
+		} else {
+			info.answerBody = fd
+		}
+	}
+}
+
+func decodeField(a *diam.AVP, m protoreflect.Message, f *fieldInfo) error {
+	fd := f.desc
+	if fd.IsList() {
+		list := m.Mutable(fd).List()
+		v, err := decodeValue(a, f, list.NewElement)
+		if err != nil {
+			return err
+		}
+		list.Append(v)
+		return nil
+	}
+	// a single member of a oneof is accepted, the AVPs being alternatives
+	if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+		if set := m.WhichOneof(od); set != nil {
+			return fmt.Errorf("AVP %d (vendor %d): alternative %s already decoded", a.Code, a.VendorID, set.Name())
+		}
+	}
+	v, err := decodeValue(a, f, func() protoreflect.Value { return m.NewField(fd) })
+	if err != nil {
+		return err
+	}
+	m.Set(fd, v)
+	return nil
+}
+
+// decodeValue converts the data of a into a value of the field, newElement
+// returning an empty message for message fields.
+func decodeValue(a *diam.AVP, f *fieldInfo, newElement func() protoreflect.Value) (protoreflect.Value, error) {
+	fd := f.desc
+	if md := fd.Message(); md != nil {
+		elem := newElement()
+		msg := elem.Message()
+		switch {
+		case md.FullName() == timestampName:
+			t, ok := a.Data.(datatype.Time)
+			if !ok {
+				return protoreflect.Value{}, unexpectedType(a)
+			}
+			setTimestamp(msg, time.Time(t))
+		case md.ParentFile().Path() == wrappersFile:
+			value := md.Fields().ByName("value")
+			v, err := scalarValue(a, value.Kind())
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			msg.Set(value, v)
+		default:
+			g, ok := a.Data.(*diam.GroupedAVP)
+			if !ok {
+				return protoreflect.Value{}, unexpectedType(a)
+			}
+			if err := DecodeAVPs(g.AVP, msg); err != nil {
+				return protoreflect.Value{}, err
+			}
+		}
+		return elem, nil
+	}
+	return scalarValue(a, fd.Kind())
+}
+
+// scalarValue converts the data of a into a scalar of the given kind.
+func scalarValue(a *diam.AVP, kind protoreflect.Kind) (protoreflect.Value, error) {
+	switch kind {
+	case protoreflect.StringKind:
+		if s, ok := stringOf(a.Data); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(bytesOf(a.Data)), nil
+	case protoreflect.BoolKind:
+		if n, ok := intOf(a.Data); ok {
+			return protoreflect.ValueOfBool(n != 0), nil
+		}
+	case protoreflect.EnumKind:
+		if n, ok := intOf(a.Data); ok {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok := intOf(a.Data); ok {
+			return protoreflect.ValueOfInt32(int32(n)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok := intOf(a.Data); ok {
+			return protoreflect.ValueOfInt64(n), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok := intOf(a.Data); ok {
+			return protoreflect.ValueOfUint32(uint32(n)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if v, ok := a.Data.(datatype.Unsigned64); ok {
+			return protoreflect.ValueOfUint64(uint64(v)), nil
+		}
+		if n, ok := intOf(a.Data); ok {
+			return protoreflect.ValueOfUint64(uint64(n)), nil
+		}
+	case protoreflect.FloatKind:
+		if x, ok := floatOf(a.Data); ok {
+			return protoreflect.ValueOfFloat32(float32(x)), nil
+		}
+	case protoreflect.DoubleKind:
+		if x, ok := floatOf(a.Data); ok {
+			return protoreflect.ValueOfFloat64(x), nil
+		}
+	}
+	return protoreflect.Value{}, unexpectedType(a)
+}
+
+func intOf(data datatype.Type) (int64, bool) {
+	switch v := data.(type) {
+	case datatype.Integer32:
+		return int64(v), true
+	case datatype.Integer64:
+		return int64(v), true
+	case datatype.Unsigned32:
+		return int64(v), true
+	case datatype.Unsigned64:
+		return int64(v), true
+	case datatype.Enumerated:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func floatOf(data datatype.Type) (float64, bool) {
+	switch v := data.(type) {
+	case datatype.Float32:
+		return float64(v), true
+	case datatype.Float64:
+		return float64(v), true
+	}
+	if n, ok := intOf(data); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func stringOf(data datatype.Type) (string, bool) {
+	switch v := data.(type) {
+	case datatype.Address:
+		return net.IP(v).String(), true
+	case datatype.IPv4:
+		return net.IP(v).String(), true
+	case datatype.IPv6:
+		return net.IP(v).String(), true
+	case datatype.OctetString:
+		return string(v), true
+	case datatype.UTF8String:
+		return string(v), true
+	case datatype.DiameterIdentity:
+		return string(v), true
+	case datatype.DiameterURI:
+		return string(v), true
+	case datatype.IPFilterRule:
+		return string(v), true
+	case datatype.QoSFilterRule:
+		return string(v), true
+	case datatype.Unknown:
+		return string(v), true
+	}
+	return "", false
+}
+
+// bytesOf returns the bytes of data. Addresses are the IP as decoded, without
+// the address family that dataOf adds back, as in the generated converters.
+func bytesOf(data datatype.Type) []byte {
+	switch v := data.(type) {
+	case datatype.Address:
+		return []byte(v)
+	case datatype.IPv4:
+		return []byte(v)
+	case datatype.IPv6:
+		return []byte(v)
+	}
+	return data.Serialize()
+}
+
+func setTimestamp(msg protoreflect.Message, t time.Time) {
+	fields := msg.Descriptor().Fields()
+	msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+	msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+}
+
+// encodeValue builds the AVP of a value of the field.
+func encodeValue(v protoreflect.Value, f *fieldInfo) (*diam.AVP, error) {
+	fd := f.desc
+	if md := fd.Message(); md != nil {
+		msg := v.Message()
+		switch {
+		case md.FullName() == timestampName:
+			fields := md.Fields()
+			t := time.Unix(msg.Get(fields.ByName("seconds")).Int(), msg.Get(fields.ByName("nanos")).Int())
+			return diam.NewAVP(f.code, f.flags, f.vendorId, datatype.Time(t)), nil
+		case md.ParentFile().Path() == wrappersFile:
+			value := md.Fields().ByName("value")
+			data, err := dataOf(msg.Get(value), value.Kind(), f.avpType)
+			if err != nil {
+				return nil, err
+			}
+			return diam.NewAVP(f.code, f.flags, f.vendorId, data), nil
+		}
+		avps, err := EncodeAVPs(msg)
+		if err != nil {
+			return nil, err
+		}
+		return diam.NewAVP(f.code, f.flags, f.vendorId, &diam.GroupedAVP{AVP: avps}), nil
+	}
+	data, err := dataOf(v, fd.Kind(), f.avpType)
+	if err != nil {
+		return nil, err
+	}
+	return diam.NewAVP(f.code, f.flags, f.vendorId, data), nil
+}
+
+// dataOf converts a scalar of the given kind into the Diameter data type.
+func dataOf(v protoreflect.Value, kind protoreflect.Kind, avpType datatype.TypeID) (datatype.Type, error) {
+	var n int64
+	var u uint64
+	var x float64
+	var s string
+	switch kind {
+	case protoreflect.StringKind:
+		s = v.String()
+	case protoreflect.BytesKind:
+		s = string(v.Bytes())
+	case protoreflect.BoolKind:
+		if v.Bool() {
+			n, u, x = 1, 1, 1
+		}
+	case protoreflect.EnumKind:
+		n = int64(v.Enum())
+		u, x = uint64(n), float64(n)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n = v.Int()
+		u, x = uint64(n), float64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		u = v.Uint()
+		n, x = int64(u), float64(u)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		x = v.Float()
+		n, u = int64(x), uint64(x)
+	default:
+		return nil, fmt.Errorf("unsupported field kind %s", kind)
+	}
+
+	switch avpType {
+	case datatype.Integer32Type:
+		return datatype.Integer32(n), nil
+	case datatype.Integer64Type:
+		return datatype.Integer64(n), nil
+	case datatype.Unsigned32Type:
+		return datatype.Unsigned32(u), nil
+	case datatype.Unsigned64Type:
+		return datatype.Unsigned64(u), nil
+	case datatype.EnumeratedType:
+		return datatype.Enumerated(n), nil
+	case datatype.Float32Type:
+		return datatype.Float32(x), nil
+	case datatype.Float64Type:
+		return datatype.Float64(x), nil
+	case datatype.AddressType, datatype.IPv4Type, datatype.IPv6Type:
+		ip := []byte(s)
+		if kind == protoreflect.StringKind {
+			ip = net.ParseIP(s)
+		}
+		switch avpType {
+		case datatype.IPv4Type:
+			return datatype.IPv4(ip), nil
+		case datatype.IPv6Type:
+			return datatype.IPv6(ip), nil
+		}
+		return datatype.Address(ip), nil
+	case datatype.UTF8StringType:
+		return datatype.UTF8String(s), nil
+	case datatype.DiameterIdentityType:
+		return datatype.DiameterIdentity(s), nil
+	case datatype.DiameterURIType:
+		return datatype.DiameterURI(s), nil
+	case datatype.IPFilterRuleType:
+		return datatype.IPFilterRule(s), nil
+	case datatype.QoSFilterRuleType:
+		return datatype.QoSFilterRule(s), nil
+	case datatype.OctetStringType:
+		return datatype.OctetString(s), nil
+	}
+	return datatype.Unknown(s), nil
+}
+
+func unexpectedType(a *diam.AVP) error {
+	return fmt.Errorf("AVP %d (vendor %d): unexpected data type %T", a.Code, a.VendorID, a.Data)
+}
@@ -0,0 +1,372 @@
+package transcoder
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fiorix/go-diameter/v4/diam"
+	"github.com/fiorix/go-diameter/v4/diam/avp"
+	"github.com/fiorix/go-diameter/v4/diam/datatype"
+	"github.com/fiorix/go-diameter/v4/diam/dict"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// annotate returns options whose unknown fields hold the Diameter options, as
+// for descriptors parsed without the generated extensions.
+func annotate[T proto.Message](opts T, varints map[protowire.Number]uint64, avpType string) T {
+	var b []byte
+	for _, num := range []protowire.Number{AvpCodeOption, VendorIdOption, MandatoryOption, RequiredOption,
+		ApplicationIdOption, CommandCodeOption, IsRequestOption} {
+		if v, ok := varints[num]; ok {
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, v)
+		}
+	}
+	if avpType != "" {
+		b = protowire.AppendTag(b, AvpTypeOption, protowire.BytesType)
+		b = protowire.AppendString(b, avpType)
+	}
+	opts.ProtoReflect().SetUnknown(b)
+	return opts
+}
+
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool,
+	code uint64, required bool, avpType string) *descriptorpb.FieldDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	if repeated {
+		label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	}
+	varints := map[protowire.Number]uint64{AvpCodeOption: code, MandatoryOption: 1}
+	if required {
+		varints[RequiredOption] = 1
+	}
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:    proto.String(name),
+		Number:  proto.Int32(number),
+		Label:   label.Enum(),
+		Type:    typ.Enum(),
+		Options: annotate(&descriptorpb.FieldOptions{}, varints, avpType),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func testDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	const (
+		str = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		u32 = descriptorpb.FieldDescriptorProto_TYPE_UINT32
+		msg = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		enm = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+		byt = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	)
+	pdpAddress := field("pdpAddress", 9, byt, "", false, 1227, false, "Address")
+	pdpAddress.Options = annotate(&descriptorpb.FieldOptions{},
+		map[protowire.Number]uint64{AvpCodeOption: 1227, VendorIdOption: 10415, MandatoryOption: 1}, "Address")
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/wrappers.proto", "google/protobuf/timestamp.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("CCRequestType"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("CC_REQUEST_TYPE_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("CC_REQUEST_TYPE_INITIAL_REQUEST"), Number: proto.Int32(1)},
+				{Name: proto.String("CC_REQUEST_TYPE_UPDATE_REQUEST"), Number: proto.Int32(2)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("SubscriptionId"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("subscriptionIdType", 1, u32, "", false, 450, true, "Enumerated"),
+					field("subscriptionIdData", 2, str, "", false, 444, true, "UTF8String"),
+				},
+			},
+			{
+				Name: proto.String("CreditControlRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("sessionId", 1, str, "", false, 263, true, "UTF8String"),
+					field("ccRequestType", 2, enm, ".test.CCRequestType", false, 416, true, "Enumerated"),
+					field("ccRequestNumber", 3, u32, "", false, 415, true, "Unsigned32"),
+					field("originStateId", 4, msg, ".google.protobuf.UInt32Value", false, 278, false, "Unsigned32"),
+					field("subscriptionId", 5, msg, ".test.SubscriptionId", true, 443, false, "Grouped"),
+					field("eventTimestamp", 6, msg, ".google.protobuf.Timestamp", false, 55, false, "Time"),
+					field("hostIPAddress", 7, str, "", true, 257, false, "Address"),
+					field("destinationHost", 8, str, "", false, 293, false, "DiameterIdentity"),
+					pdpAddress,
+				},
+				Options: annotate(&descriptorpb.MessageOptions{}, map[protowire.Number]uint64{
+					ApplicationIdOption: 4, CommandCodeOption: 272, IsRequestOption: 1,
+				}, ""),
+			},
+			{
+				Name: proto.String("Destination"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("destinationHost", 1, str, "", false, 293, false, "DiameterIdentity"),
+					field("destinationRealm", 2, str, "", false, 283, false, "DiameterIdentity"),
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("destination")}},
+			},
+		},
+	}
+	for _, f := range file.MessageType[2].Field {
+		f.OneofIndex = proto.Int32(0)
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Messages().ByName("CreditControlRequest")
+}
+
+func TestRoundTrip(t *testing.T) {
+	md := testDescriptor(t)
+	fields := md.Fields()
+	in := dynamicpb.NewMessage(md)
+	in.Set(fields.ByName("sessionId"), protoreflect.ValueOfString("session;1"))
+	in.Set(fields.ByName("ccRequestType"), protoreflect.ValueOfEnum(2))
+	in.Set(fields.ByName("originStateId"), protoreflect.ValueOfMessage(wrapperspb.UInt32(7).ProtoReflect()))
+	in.Set(fields.ByName("eventTimestamp"), protoreflect.ValueOfMessage(
+		timestamppb.New(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)).ProtoReflect()))
+	subscriptions := in.Mutable(fields.ByName("subscriptionId")).List()
+	for _, data := range []string{"123", "456"} {
+		s := subscriptions.NewElement()
+		s.Message().Set(md.ParentFile().Messages().ByName("SubscriptionId").Fields().ByName("subscriptionIdData"),
+			protoreflect.ValueOfString(data))
+		subscriptions.Append(s)
+	}
+	ips := in.Mutable(fields.ByName("hostIPAddress")).List()
+	ips.Append(protoreflect.ValueOfString("10.0.0.1"))
+	in.Set(fields.ByName("pdpAddress"), protoreflect.ValueOfBytes([]byte{10, 0, 0, 2}))
+
+	msg, err := Encode(in, dict.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.CommandCode != 272 || msg.Header.ApplicationID != 4 || msg.Header.CommandFlags&diam.RequestFlag == 0 {
+		t.Errorf("unexpected header %s", msg.Header)
+	}
+	// required fields are encoded even with the zero value, others are not
+	if a, err := msg.FindAVP(415, 0); err != nil || a.Data != datatype.Unsigned32(0) {
+		t.Errorf("CC-Request-Number: %v %v", a, err)
+	}
+	if a, err := msg.FindAVP(263, 0); err != nil || a.Flags&avp.Mbit == 0 {
+		t.Errorf("Session-Id: %v %v", a, err)
+	}
+	if _, err := msg.FindAVP(293, 0); err == nil {
+		t.Error("unset Destination-Host was encoded")
+	}
+	// address bytes are the IP, the family being added on encoding
+	if a, err := msg.FindAVP(1227, 10415); err != nil || !bytes.Equal(a.Data.Serialize(), []byte{0, 1, 10, 0, 0, 2}) {
+		t.Errorf("PDP-Address: %v %v", a, err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := diam.ReadMessage(&buf, dict.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := dynamicpb.NewMessage(md)
+	if err := Decode(read, out); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(in, out) {
+		t.Errorf("round trip mismatch\nin:  %v\nout: %v", in, out)
+	}
+}
+
+func TestEncodeNotCommand(t *testing.T) {
+	md := testDescriptor(t).ParentFile().Messages().ByName("SubscriptionId")
+	if _, err := Encode(dynamicpb.NewMessage(md), dict.Default); err == nil {
+		t.Error("expected an error encoding a message without command options")
+	}
+}
+
+func TestDecodeAlternatives(t *testing.T) {
+	md := testDescriptor(t).ParentFile().Messages().ByName("Destination")
+	avps := []*diam.AVP{diam.NewAVP(293, avp.Mbit, 0, datatype.DiameterIdentity("host"))}
+	m := dynamicpb.NewMessage(md)
+	if err := DecodeAVPs(avps, m); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Has(md.Fields().ByName("destinationHost")) {
+		t.Error("alternative not decoded")
+	}
+	avps = append(avps, diam.NewAVP(283, avp.Mbit, 0, datatype.DiameterIdentity("realm")))
+	if err := DecodeAVPs(avps, dynamicpb.NewMessage(md)); err == nil {
+		t.Error("expected an error decoding two alternatives")
+	}
+}
+
+func TestUnknownAVPs(t *testing.T) {
+	const (
+		str = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		u32 = descriptorpb.FieldDescriptorProto_TYPE_UINT32
+		byt = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+		msg = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	raw := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(),
+			Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()}
+	}
+	unknown := raw("unknown_avps", 536870911, msg)
+	unknown.Label, unknown.TypeName = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), proto.String(".diameter.RawAvp")
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("diameter/raw.proto"),
+		Package: proto.String("diameter"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("RawAvp"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					raw("code", 1, u32), raw("vendor_id", 2, u32), raw("flags", 3, u32), raw("data", 4, byt),
+				},
+			},
+			{
+				Name:  proto.String("Holder"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("sessionId", 1, str, "", false, 263, true, "UTF8String"), unknown},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := fd.Messages().ByName("Holder")
+
+	in := diam.NewMessage(272, diam.RequestFlag, 4, 1, 1, dict.Default)
+	in.NewAVP(263, avp.Mbit, 0, datatype.UTF8String("session;1"))
+	in.NewAVP(264, avp.Mbit, 0, datatype.DiameterIdentity("host"))
+	in.NewAVP(99999, avp.Mbit|avp.Vbit, 10415, datatype.OctetString("vendor"))
+	var buf bytes.Buffer
+	if _, err := in.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := diam.ReadMessage(&buf, dict.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := Decode(read, m); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Get(md.Fields().ByName("unknown_avps")).List().Len(); n != 2 {
+		t.Fatalf("got %d unknown AVPs, want Origin-Host and the vendor AVP", n)
+	}
+	avps, err := EncodeAVPs(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(avps) != len(in.AVP) {
+		t.Fatalf("got %d AVPs, want %d", len(avps), len(in.AVP))
+	}
+	for i, a := range avps {
+		want, _ := in.AVP[i].Serialize()
+		got, _ := a.Serialize()
+		if !bytes.Equal(got, want) {
+			t.Errorf("AVP %d: got %x, want %x", a.Code, got, want)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	const (
+		str = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		u32 = descriptorpb.FieldDescriptorProto_TYPE_UINT32
+		bln = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+		msg = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	plain := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(),
+			Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	request := plain("request", 2, msg, ".test.CreditControlRequest")
+	request.OneofIndex = proto.Int32(0)
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("diameter/envelope.proto"),
+		Package:    proto.String("diameter"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"test.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("DiameterHeader"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					plain("application_id", 1, u32, ""), plain("command_code", 2, u32, ""),
+					plain("request", 3, bln, ""), plain("proxiable", 4, bln, ""),
+					plain("error", 5, bln, ""), plain("retransmitted", 6, bln, ""),
+					plain("hop_by_hop_id", 7, u32, ""), plain("end_to_end_id", 8, u32, ""),
+					plain("session_id", 9, str, ""),
+				},
+			},
+			{
+				Name:      proto.String("CreditControlEnvelope"),
+				Field:     []*descriptorpb.FieldDescriptorProto{plain("header", 1, msg, ".diameter.DiameterHeader"), request},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("body")}},
+			},
+		},
+	}
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(testDescriptor(t).ParentFile()); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := protodesc.NewFile(file, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := fd.Messages().ByName("CreditControlEnvelope")
+	hd := fd.Messages().ByName("DiameterHeader").Fields()
+
+	in := dynamicpb.NewMessage(md)
+	header := in.Mutable(md.Fields().ByName("header")).Message()
+	header.Set(hd.ByName("hop_by_hop_id"), protoreflect.ValueOfUint32(7))
+	header.Set(hd.ByName("end_to_end_id"), protoreflect.ValueOfUint32(9))
+	header.Set(hd.ByName("proxiable"), protoreflect.ValueOfBool(true))
+	body := in.Mutable(md.Fields().ByName("request")).Message()
+	body.Set(body.Descriptor().Fields().ByName("sessionId"), protoreflect.ValueOfString("session;1"))
+
+	m, err := Encode(in, dict.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Header.HopByHopID != 7 || m.Header.EndToEndID != 9 || m.Header.CommandFlags != diam.RequestFlag|diam.ProxiableFlag {
+		t.Errorf("unexpected header %s", m.Header)
+	}
+
+	out := dynamicpb.NewMessage(md)
+	if err := Decode(m, out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.Get(md.Fields().ByName("header")).Message()
+	if got.Get(hd.ByName("command_code")).Uint() != 272 || !got.Get(hd.ByName("request")).Bool() ||
+		got.Get(hd.ByName("session_id")).String() != "session;1" {
+		t.Errorf("unexpected decoded header %v", got)
+	}
+	if !proto.Equal(out.Get(md.Fields().ByName("request")).Message().Interface(), body.Interface()) {
+		t.Errorf("request mismatch: %v", out)
+	}
+
+	m.Header.CommandFlags &^= diam.RequestFlag
+	if err := Decode(m, dynamicpb.NewMessage(md)); err == nil {
+		t.Error("expected an error decoding an answer without an answer field")
+	}
+}